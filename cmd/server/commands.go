@@ -0,0 +1,98 @@
+package main
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+)
+
+// runCommand runs a maintenance subcommand instead of the server. It returns
+// false when args don't name one.
+func runCommand(cfg *config.Config, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "reindex":
+		runReindex(cfg, args[1:])
+	case "reconcile":
+		runReconcile(cfg, args[1:])
+	case "grant-admin":
+		runGrantAdmin(cfg, args[1:])
+	default:
+		return false
+	}
+	return true
+}
+
+// runReindex rebuilds the Elasticsearch posts index from Postgres and swaps
+// the aliases to it: ./main reindex [-batch-size 500] [-delete-old]
+func runReindex(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	batchSize := flags.Int("batch-size", 500, "posts per bulk request")
+	deleteOld := flags.Bool("delete-old", false, "delete the previous index after the swap")
+	flags.Parse(args)
+
+	if err := initializeDatabases(cfg); err != nil {
+		log.Fatal("Failed to initialize databases:", err)
+	}
+
+	report, err := services.NewReindexService(*batchSize).Reindex(context.Background(), *deleteOld)
+	if err != nil {
+		log.Fatal("Reindex failed:", err)
+	}
+
+	log.Printf("Reindexed %d posts into %s in %s (caught up %d updated, %d deleted; %d saved searches; previous: %v)",
+		report.Indexed, report.Index, report.Duration, report.CaughtUp, report.Deleted, report.SavedSearches, report.PreviousIndices)
+}
+
+// runReconcile compares Postgres with Elasticsearch and Redis, repairs the
+// drift and prints the report as JSON: ./main reconcile [-dry-run]
+func runReconcile(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report drift without repairing it")
+	flags.Parse(args)
+
+	if err := initializeDatabases(cfg); err != nil {
+		log.Fatal("Failed to initialize databases:", err)
+	}
+
+	report, err := services.NewReconcileService(&cfg.Reconcile).Reconcile(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatal("Reconcile failed:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runGrantAdmin makes a registered user an admin, which is how a new deploy
+// gets its first admin: ./main grant-admin -email <email>
+func runGrantAdmin(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("grant-admin", flag.ExitOnError)
+	email := flags.String("email", "", "email of the user to make an admin")
+	flags.Parse(args)
+
+	if *email == "" {
+		log.Fatal("grant-admin needs -email")
+	}
+
+	if err := database.InitDB(&cfg.Database); err != nil {
+		log.Fatal("Failed to connect to PostgreSQL:", err)
+	}
+
+	user, err := services.NewUserService().GrantAdmin(*email)
+	if err != nil {
+		log.Fatal("Grant admin failed:", err)
+	}
+	log.Printf("User %d (%s) is now an admin", user.ID, user.Email)
+}
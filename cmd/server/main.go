@@ -1,106 +1,201 @@
-package main
-
-import (
-	"blog-api/internal/config"
-	"blog-api/internal/database"
-	"blog-api/internal/handlers"
-	"blog-api/internal/middleware"
-	"log"
-	"time"
-
-	"github.com/gin-gonic/gin"
-)
-
-func main() {
-	// Load configuration
-	cfg := config.LoadConfig()
-
-	// Initialize databases with retry logic
-	if err := initializeDatabases(cfg); err != nil {
-		log.Fatal("Failed to initialize databases:", err)
-	}
-
-	// Set up Gin router
-	router := setupRouter()
-
-	// Start server
-	log.Printf("Server starting on port %s", cfg.Server.Port)
-	if err := router.Run(":" + cfg.Server.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
-	}
-}
-
-func initializeDatabases(cfg *config.Config) error {
-	// Initialize PostgreSQL with retry
-	for i := 0; i < 5; i++ {
-		if err := database.InitDB(&cfg.Database); err != nil {
-			log.Printf("Failed to connect to PostgreSQL (attempt %d/5): %v", i+1, err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		break
-	}
-
-	// Initialize Redis with retry
-	for i := 0; i < 5; i++ {
-		if err := database.InitRedis(&cfg.Redis); err != nil {
-			log.Printf("Failed to connect to Redis (attempt %d/5): %v", i+1, err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		break
-	}
-
-	// Initialize Elasticsearch with retry
-	for i := 0; i < 5; i++ {
-		if err := database.InitElasticsearch(&cfg.Elasticsearch); err != nil {
-			log.Printf("Failed to connect to Elasticsearch (attempt %d/5): %v", i+1, err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		break
-	}
-
-	log.Println("All databases initialized successfully")
-	return nil
-}
-
-func setupRouter() *gin.Engine {
-	// Set to release mode in production
-	gin.SetMode(gin.ReleaseMode)
-
-	router := gin.New()
-
-	// Add middleware
-	router.Use(middleware.Logger())
-	router.Use(middleware.Recovery())
-	router.Use(middleware.CORS())
-
-	// Initialize handlers
-	postHandler := handlers.NewPostHandler()
-
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Blog API is running",
-		})
-	})
-
-	// API routes
-	v1 := router.Group("/api/v1")
-	{
-		posts := v1.Group("/posts")
-		{
-			posts.POST("", postHandler.CreatePost)
-			posts.GET("", postHandler.GetAllPosts)
-			posts.GET("/:id", postHandler.GetPost)
-			posts.PUT("/:id", postHandler.UpdatePost)
-			posts.DELETE("/:id", postHandler.DeletePost)
-			posts.GET("/search-by-tag", postHandler.SearchPostsByTag)
-			posts.GET("/search", postHandler.SearchPosts)
-		}
-	}
-
-	return router
+package main
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/handlers"
+	"blog-api/internal/middleware"
+	"blog-api/internal/models"
+	"blog-api/internal/services"
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.LoadConfig()
+
+	// Maintenance subcommands, e.g. "reindex", run and exit
+	if runCommand(cfg, os.Args[1:]) {
+		return
+	}
+
+	if cfg.Auth.JWTSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+
+	// Initialize databases with retry logic
+	if err := initializeDatabases(cfg); err != nil {
+		log.Fatal("Failed to initialize databases:", err)
+	}
+
+	// Give posts created before slugs existed a slug
+	if err := services.BackfillPostSlugs(); err != nil {
+		log.Printf("Failed to backfill post slugs: %v", err)
+	}
+
+	// Keep a local post cache in front of Redis, coherent across replicas
+	services.InitLocalCache(&cfg.Cache)
+	go services.NewCacheInvalidationListener().Run(context.Background())
+
+	// Deliver queued search/cache side effects in the background
+	go services.NewOutboxService(&cfg.Outbox).Run(context.Background())
+
+	// Publish scheduled posts when their publish_at is reached
+	go services.NewPublishScheduler(&cfg.Scheduler).Run(context.Background())
+
+	// Repair drift between Postgres, the search index and the cache
+	if cfg.Reconcile.Interval > 0 && cfg.Elasticsearch.Enabled {
+		go services.NewReconcileService(&cfg.Reconcile).Run(context.Background())
+	}
+
+	// Set up Gin router
+	router := setupRouter(cfg)
+
+	// Start server
+	log.Printf("Server starting on port %s", cfg.Server.Port)
+	if err := router.Run(":" + cfg.Server.Port); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
+
+func initializeDatabases(cfg *config.Config) error {
+	// Initialize PostgreSQL with retry
+	for i := 0; i < 5; i++ {
+		if err := database.InitDB(&cfg.Database); err != nil {
+			log.Printf("Failed to connect to PostgreSQL (attempt %d/5): %v", i+1, err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		break
+	}
+
+	// Initialize Redis with retry
+	for i := 0; i < 5; i++ {
+		if err := database.InitRedis(&cfg.Redis); err != nil {
+			log.Printf("Failed to connect to Redis (attempt %d/5): %v", i+1, err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		break
+	}
+
+	// Initialize Elasticsearch with retry
+	for i := 0; i < 5; i++ {
+		if err := database.InitElasticsearch(&cfg.Elasticsearch); err != nil {
+			log.Printf("Failed to connect to Elasticsearch (attempt %d/5): %v", i+1, err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		break
+	}
+
+	log.Println("All databases initialized successfully")
+	return nil
+}
+
+func setupRouter(cfg *config.Config) *gin.Engine {
+	// Set to release mode in production
+	gin.SetMode(gin.ReleaseMode)
+
+	router := gin.New()
+
+	// Add middleware
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Logger())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.CORS())
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(&cfg.Auth)
+	postHandler := handlers.NewPostHandler()
+	revisionHandler := handlers.NewRevisionHandler()
+	outboxHandler := handlers.NewOutboxHandler(&cfg.Outbox)
+	userHandler := handlers.NewUserHandler()
+	activityHandler := handlers.NewActivityHandler()
+	searchAnalyticsHandler := handlers.NewSearchAnalyticsHandler()
+	savedSearchHandler := handlers.NewSavedSearchHandler()
+	cacheHandler := handlers.NewCacheHandler()
+
+	// Mutating endpoints require a signed-in user; reads identify the user
+	// when a token is sent so authors and editors can see unpublished posts
+	authService := services.NewAuthService(&cfg.Auth)
+	requireAuth := middleware.AuthRequired(authService)
+	optionalAuth := middleware.OptionalAuth(authService)
+
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status":  "ok",
+			"message": "Blog API is running",
+		})
+	})
+
+	// API routes
+	v1 := router.Group("/api/v1")
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.GET("/me", requireAuth, authHandler.Me)
+		}
+
+		posts := v1.Group("/posts")
+		{
+			posts.POST("", requireAuth, postHandler.CreatePost)
+			posts.GET("", optionalAuth, postHandler.GetAllPosts)
+			posts.GET("/:id", optionalAuth, postHandler.GetPost)
+			posts.GET("/by-slug/:slug", optionalAuth, postHandler.GetPostBySlug)
+			posts.PUT("/:id", requireAuth, postHandler.UpdatePost)
+			posts.DELETE("/:id", requireAuth, postHandler.DeletePost)
+			posts.GET("/search-by-tag", optionalAuth, postHandler.SearchPostsByTag)
+			posts.GET("/search", optionalAuth, postHandler.SearchPosts)
+			posts.POST("/search/clicks", optionalAuth, postHandler.RecordSearchClick)
+			posts.GET("/suggest", optionalAuth, postHandler.SuggestPosts)
+
+			posts.GET("/:id/revisions", requireAuth, revisionHandler.ListRevisions)
+			posts.GET("/:id/revisions/diff", requireAuth, revisionHandler.DiffRevisions)
+			posts.GET("/:id/revisions/:version", requireAuth, revisionHandler.GetRevision)
+			posts.POST("/:id/revisions/:version/restore", requireAuth, revisionHandler.RestoreRevision)
+		}
+
+		outbox := v1.Group("/outbox", requireAuth, middleware.RequireRole(models.RoleAdmin))
+		{
+			outbox.GET("", outboxHandler.ListEvents)
+			outbox.POST("/:id/retry", outboxHandler.RetryEvent)
+		}
+
+		v1.GET("/activity", requireAuth, activityHandler.ListActivity)
+		v1.GET("/cache/stats", requireAuth, middleware.RequireRole(models.RoleAdmin), cacheHandler.Stats)
+
+		me := v1.Group("/me", requireAuth)
+		{
+			me.GET("/saved-searches", savedSearchHandler.ListSavedSearches)
+			me.POST("/saved-searches", savedSearchHandler.CreateSavedSearch)
+			me.DELETE("/saved-searches/:id", savedSearchHandler.DeleteSavedSearch)
+			me.GET("/notifications", savedSearchHandler.ListNotifications)
+			me.POST("/notifications/:id/read", savedSearchHandler.MarkNotificationRead)
+		}
+
+		searchAnalytics := v1.Group("/analytics/search", requireAuth, middleware.RequireRole(models.RoleAdmin))
+		{
+			searchAnalytics.GET("/top-queries", searchAnalyticsHandler.TopQueries)
+			searchAnalytics.GET("/zero-results", searchAnalyticsHandler.ZeroResultQueries)
+			searchAnalytics.GET("/click-through", searchAnalyticsHandler.ClickThroughRates)
+		}
+
+		users := v1.Group("/users", requireAuth)
+		{
+			users.GET("", userHandler.ListUsers)
+			users.PUT("/:id/role", userHandler.UpdateRole)
+		}
+	}
+
+	return router
 }
\ No newline at end of file
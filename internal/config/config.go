@@ -1,78 +1,206 @@
-package config
-
-import (
-	"fmt"
-	"os"
-)
-
-type Config struct {
-	Database      DatabaseConfig
-	Redis         RedisConfig
-	Elasticsearch ElasticsearchConfig
-	Server        ServerConfig
-}
-
-type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-}
-
-type RedisConfig struct {
-	Host string
-	Port string
-}
-
-type ElasticsearchConfig struct {
-	Host string
-	Port string
-}
-
-type ServerConfig struct {
-	Port string
-}
-
-func LoadConfig() *Config {
-	return &Config{
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "blog_user"),
-			Password: getEnv("DB_PASSWORD", "blog_password"),
-			DBName:   getEnv("DB_NAME", "blog_db"),
-		},
-		Redis: RedisConfig{
-			Host: getEnv("REDIS_HOST", "localhost"),
-			Port: getEnv("REDIS_PORT", "6379"),
-		},
-		Elasticsearch: ElasticsearchConfig{
-			Host: getEnv("ELASTICSEARCH_HOST", "localhost"),
-			Port: getEnv("ELASTICSEARCH_PORT", "9200"),
-		},
-		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-		},
-	}
-}
-
-func (d *DatabaseConfig) DSN() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Ho_Chi_Minh",
-		d.Host, d.Port, d.User, d.Password, d.DBName)
-}
-
-func (r *RedisConfig) Address() string {
-	return fmt.Sprintf("%s:%s", r.Host, r.Port)
-}
-
-func (e *ElasticsearchConfig) URL() string {
-	return fmt.Sprintf("http://%s:%s", e.Host, e.Port)
-}
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Database      DatabaseConfig
+	Redis         RedisConfig
+	Cache         CacheConfig
+	Elasticsearch ElasticsearchConfig
+	Server        ServerConfig
+	Outbox        OutboxConfig
+	Scheduler     SchedulerConfig
+	Reconcile     ReconcileConfig
+	Auth          AuthConfig
+}
+
+type DatabaseConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	DBName   string
+}
+
+type RedisConfig struct {
+	Host string
+	Port string
+}
+
+// CacheConfig sizes the in-process cache kept in front of Redis. A LocalSize
+// of 0 disables it. LocalTTL bounds how stale an entry can get if an
+// invalidation message is lost.
+type CacheConfig struct {
+	LocalSize int
+	LocalTTL  time.Duration
+}
+
+type ElasticsearchConfig struct {
+	// Enabled false runs without Elasticsearch: searches use Postgres full-text
+	// search and nothing is indexed
+	Enabled bool
+	Host    string
+	Port    string
+	// IndexAlias is the read alias of the posts index; writes go through
+	// "<IndexAlias>_write". Both point at a versioned index "<IndexAlias>_vN".
+	IndexAlias string
+	// Text analysis of the posts index. Stopwords are matched after diacritics
+	// are folded, so list them without accents. SynonymsPath is relative to the
+	// Elasticsearch config directory and must exist on every node. UseICU needs
+	// the analysis-icu plugin.
+	Stopwords    []string
+	SynonymsPath string
+	UseICU       bool
+}
+
+type ServerConfig struct {
+	Port string
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// ClaimTimeout is how long a dispatcher has to deliver the events it
+	// claimed before other dispatchers may claim them again
+	ClaimTimeout time.Duration
+}
+
+type SchedulerConfig struct {
+	PublishInterval time.Duration
+}
+
+// ReconcileConfig schedules the consistency check between Postgres,
+// Elasticsearch and Redis. An Interval of 0 disables the scheduled run.
+type ReconcileConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+type AuthConfig struct {
+	JWTSecret       string
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+func LoadConfig() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Host:     getEnv("DB_HOST", "localhost"),
+			Port:     getEnv("DB_PORT", "5432"),
+			User:     getEnv("DB_USER", "blog_user"),
+			Password: getEnv("DB_PASSWORD", "blog_password"),
+			DBName:   getEnv("DB_NAME", "blog_db"),
+		},
+		Redis: RedisConfig{
+			Host: getEnv("REDIS_HOST", "localhost"),
+			Port: getEnv("REDIS_PORT", "6379"),
+		},
+		Cache: CacheConfig{
+			LocalSize: getEnvInt("CACHE_LOCAL_SIZE", 1000),
+			LocalTTL:  getEnvDuration("CACHE_LOCAL_TTL", 30*time.Second),
+		},
+		Elasticsearch: ElasticsearchConfig{
+			Enabled: getEnvBool("ELASTICSEARCH_ENABLED", true),
+			Host:    getEnv("ELASTICSEARCH_HOST", "localhost"),
+			Port:    getEnv("ELASTICSEARCH_PORT", "9200"),
+
+			IndexAlias: getEnv("ELASTICSEARCH_INDEX_ALIAS", "posts"),
+
+			Stopwords:    getEnvList("ELASTICSEARCH_STOPWORDS"),
+			SynonymsPath: getEnv("ELASTICSEARCH_SYNONYMS_PATH", ""),
+			UseICU:       getEnvBool("ELASTICSEARCH_USE_ICU", false),
+		},
+		Server: ServerConfig{
+			Port: getEnv("SERVER_PORT", "8080"),
+		},
+		Outbox: OutboxConfig{
+			PollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 2*time.Second),
+			BatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 50),
+			MaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+			BaseBackoff:  getEnvDuration("OUTBOX_BASE_BACKOFF", 5*time.Second),
+			MaxBackoff:   getEnvDuration("OUTBOX_MAX_BACKOFF", 30*time.Minute),
+			ClaimTimeout: getEnvDuration("OUTBOX_CLAIM_TIMEOUT", time.Minute),
+		},
+		Scheduler: SchedulerConfig{
+			PublishInterval: getEnvDuration("PUBLISH_SCHEDULER_INTERVAL", 30*time.Second),
+		},
+		Reconcile: ReconcileConfig{
+			Interval:  getEnvDuration("RECONCILE_INTERVAL", time.Hour),
+			BatchSize: getEnvInt("RECONCILE_BATCH_SIZE", 500),
+		},
+		Auth: AuthConfig{
+			JWTSecret:       getEnv("JWT_SECRET", ""),
+			Issuer:          getEnv("JWT_ISSUER", "blog-api"),
+			AccessTokenTTL:  getEnvDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvDuration("JWT_REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		},
+	}
+}
+
+func (d *DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Ho_Chi_Minh",
+		d.Host, d.Port, d.User, d.Password, d.DBName)
+}
+
+func (r *RedisConfig) Address() string {
+	return fmt.Sprintf("%s:%s", r.Host, r.Port)
+}
+
+func (e *ElasticsearchConfig) URL() string {
+	return fmt.Sprintf("http://%s:%s", e.Host, e.Port)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, skipping empty items
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
\ No newline at end of file
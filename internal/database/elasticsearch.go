@@ -1,147 +1,436 @@
-package database
-
-import (
-	"blog-api/internal/config"
-	"context"
-	"encoding/json"
-	"log"
-
-	"github.com/olivere/elastic/v7"
-)
-
-var esClient *elastic.Client
-
-func ConnectElasticsearch(cfg *config.ElasticsearchConfig) (*elastic.Client, error) {
-	client, err := elastic.NewClient(
-		elastic.SetURL(cfg.URL()),
-		elastic.SetSniff(false),
-		elastic.SetHealthcheck(false),
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	// Test connection
-	ctx := context.Background()
-	_, _, err = client.Ping(cfg.URL()).Do(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Println("Connected to Elasticsearch successfully")
-
-	// Create posts index if it doesn't exist
-	exists, err := client.IndexExists("posts").Do(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	if !exists {
-		mapping := `{
-			"mappings": {
-				"properties": {
-					"id": {
-						"type": "integer"
-					},
-					"title": {
-						"type": "text",
-						"analyzer": "standard"
-					},
-					"content": {
-						"type": "text",
-						"analyzer": "standard"
-					},
-					"tags": {
-						"type": "keyword"
-					}
-				}
-			}
-		}`
-
-		_, err = client.CreateIndex("posts").BodyString(mapping).Do(ctx)
-		if err != nil {
-			return nil, err
-		}
-		log.Println("Created posts index in Elasticsearch")
-	}
-
-	return client, nil
-}
-
-func InitElasticsearch(cfg *config.ElasticsearchConfig) error {
-	var err error
-	esClient, err = ConnectElasticsearch(cfg)
-	return err
-}
-
-func GetElasticsearch() *elastic.Client {
-	return esClient
-}
-
-// IndexPost indexes a post in Elasticsearch
-func IndexPost(post interface{}, id string) error {
-	ctx := context.Background()
-	_, err := esClient.Index().
-		Index("posts").
-		Id(id).
-		BodyJson(post).
-		Do(ctx)
-	return err
-}
-
-// DeletePost removes a post from Elasticsearch
-func DeletePost(id string) error {
-	ctx := context.Background()
-	_, err := esClient.Delete().
-		Index("posts").
-		Id(id).
-		Do(ctx)
-	return err
-}
-
-// SearchPosts searches for posts in Elasticsearch
-func SearchPosts(query string) (*elastic.SearchResult, error) {
-	ctx := context.Background()
-	
-	multiMatchQuery := elastic.NewMultiMatchQuery(query, "title", "content").
-		Type("best_fields").
-		Fuzziness("AUTO")
-
-	searchResult, err := esClient.Search().
-		Index("posts").
-		Query(multiMatchQuery).
-		Sort("_score", false).
-		From(0).
-		Size(50).
-		Do(ctx)
-
-	return searchResult, err
-}
-
-// FindRelatedPosts finds posts with similar tags
-func FindRelatedPosts(tags []string, excludeID uint, limit int) (*elastic.SearchResult, error) {
-	ctx := context.Background()
-
-	boolQuery := elastic.NewBoolQuery()
-	
-	// Add should clauses for each tag
-	for _, tag := range tags {
-		boolQuery = boolQuery.Should(elastic.NewTermQuery("tags", tag))
-	}
-	
-	// Exclude the current post
-	boolQuery = boolQuery.MustNot(elastic.NewTermQuery("id", excludeID))
-	
-	// Set minimum should match to at least 1
-	boolQuery = boolQuery.MinimumShouldMatch("1")
-
-	searchResult, err := esClient.Search().
-		Index("posts").
-		Query(boolQuery).
-		Sort("_score", false).
-		From(0).
-		Size(limit).
-		Do(ctx)
-
-	return searchResult, err
+package database
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/olivere/elastic/v7"
+)
+
+var (
+	esClient   *elastic.Client
+	esDisabled bool
+)
+
+// ErrElasticsearchUnavailable is returned when the client was never initialized
+var ErrElasticsearchUnavailable = errors.New("elasticsearch client is not initialized")
+
+func ConnectElasticsearch(cfg *config.ElasticsearchConfig) (*elastic.Client, error) {
+	client, err := elastic.NewClient(
+		elastic.SetURL(cfg.URL()),
+		elastic.SetSniff(false),
+		elastic.SetHealthcheck(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test connection
+	ctx := context.Background()
+	_, _, err = client.Ping(cfg.URL()).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Connected to Elasticsearch successfully")
+
+	postsAlias = cfg.IndexAlias
+	postsWriteAlias = cfg.IndexAlias + "_write"
+	postsIndexBody, err = buildPostsIndexBody(cfg)
+	if err != nil {
+		return nil, err
+	}
+	percolatorIndex = cfg.IndexAlias + "_percolator"
+	percolatorIndexBody, err = buildPercolatorIndexBody(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the versioned posts index and its aliases on a fresh cluster
+	if err := ensurePostsIndex(ctx, client); err != nil {
+		return nil, err
+	}
+	if err := ensurePercolatorIndex(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func InitElasticsearch(cfg *config.ElasticsearchConfig) error {
+	if !cfg.Enabled {
+		esDisabled = true
+		log.Println("Elasticsearch is disabled; searching with PostgreSQL")
+		return nil
+	}
+
+	var err error
+	esClient, err = ConnectElasticsearch(cfg)
+	return err
+}
+
+// ElasticsearchDisabled reports whether Elasticsearch was turned off by
+// configuration, as opposed to being unreachable
+func ElasticsearchDisabled() bool {
+	return esDisabled
+}
+
+func GetElasticsearch() *elastic.Client {
+	return esClient
+}
+
+// IndexPost indexes a post in Elasticsearch
+func IndexPost(post interface{}, id string) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+	_, err := esClient.Index().
+		Index(postsWriteAlias).
+		Id(id).
+		BodyJson(post).
+		Do(ctx)
+	return err
+}
+
+// DeletePost removes a post from Elasticsearch
+func DeletePost(id string) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+	_, err := esClient.Delete().
+		Index(postsWriteAlias).
+		Id(id).
+		Do(ctx)
+	if elastic.IsNotFound(err) {
+		// Already gone, nothing to do
+		return nil
+	}
+	return err
+}
+
+// PostVisibility describes which unpublished posts a search may return
+type PostVisibility struct {
+	IncludeAllDrafts bool
+	DraftsByAuthorID *uint
+}
+
+// visibilityFilter matches the posts allowed by v, or returns nil when every
+// post is visible. Documents indexed before posts had a status count as
+// published, since those rows were all backfilled as published.
+func visibilityFilter(v PostVisibility) elastic.Query {
+	if v.IncludeAllDrafts {
+		return nil
+	}
+
+	filter := elastic.NewBoolQuery().
+		Should(
+			elastic.NewTermQuery("status", models.PostStatusPublished),
+			elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("status")),
+		).
+		MinimumShouldMatch("1")
+	if v.DraftsByAuthorID != nil {
+		filter = filter.Should(elastic.NewTermQuery("author_id", *v.DraftsByAuthorID))
+	}
+	return filter
+}
+
+// Aggregation names of the search facets, and the name of the spelling
+// suggester that runs with every search
+const (
+	FacetTags        = "facet_tags"
+	FacetMonths      = "facet_months"
+	SearchDidYouMean = "did_you_mean"
+)
+
+// PostSearch holds the query, facet filters and paging of a full-text search.
+// SearchAfter, when set, continues after the sort values of the last hit of a
+// previous page and From must then be 0.
+type PostSearch struct {
+	Query       string
+	Tags        []string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	From        int
+	Size        int
+	SearchAfter []interface{}
+}
+
+// SearchPosts searches for posts in Elasticsearch, sorted by score with the
+// post ID as tie-breaker so search_after pages are stable
+func SearchPosts(search PostSearch, visibility PostVisibility) (*elastic.SearchResult, error) {
+	if esClient == nil {
+		return nil, ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+
+	// Folded fields match with or without diacritics; the exact subfields
+	// lift results whose accents match the query as typed
+	multiMatchQuery := elastic.NewMultiMatchQuery(search.Query, "title", "title.exact^2", "content", "content.exact^2").
+		Type("best_fields").
+		Fuzziness("AUTO")
+
+	boolQuery := elastic.NewBoolQuery().Must(multiMatchQuery)
+	if filter := visibilityFilter(visibility); filter != nil {
+		boolQuery = boolQuery.Filter(filter)
+	}
+
+	// Facet filters are post filters: they narrow the hits but not the
+	// aggregations, which apply every facet filter except their own
+	tagFilter := elastic.NewBoolQuery()
+	for _, tag := range search.Tags {
+		tagFilter = tagFilter.Filter(elastic.NewTermQuery("tags", tag))
+	}
+	dateFilter := elastic.NewBoolQuery()
+	if search.CreatedFrom != nil || search.CreatedTo != nil {
+		createdAt := elastic.NewRangeQuery("created_at")
+		if search.CreatedFrom != nil {
+			createdAt = createdAt.Gte(search.CreatedFrom.Format(time.RFC3339))
+		}
+		if search.CreatedTo != nil {
+			createdAt = createdAt.Lte(search.CreatedTo.Format(time.RFC3339))
+		}
+		dateFilter = dateFilter.Filter(createdAt)
+	}
+
+	tagsFacet := elastic.NewFilterAggregation().
+		Filter(dateFilter).
+		SubAggregation("buckets", elastic.NewTermsAggregation().Field("tags").Size(20))
+	monthsFacet := elastic.NewFilterAggregation().
+		Filter(tagFilter).
+		SubAggregation("buckets", elastic.NewDateHistogramAggregation().
+			Field("created_at").
+			CalendarInterval("month").
+			Format("yyyy-MM").
+			MinDocCount(1))
+
+	highlight := elastic.NewHighlight().
+		Fields(
+			elastic.NewHighlighterField("title").NumOfFragments(0),
+			elastic.NewHighlighterField("content").FragmentSize(150).NumOfFragments(3),
+		).
+		PreTags("<em>").
+		PostTags("</em>")
+
+	didYouMean, err := spellingSuggester(search.Query, visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	service := esClient.Search().
+		Index(postsAlias).
+		Query(boolQuery).
+		Suggester(didYouMean).
+		PostFilter(elastic.NewBoolQuery().Filter(tagFilter, dateFilter)).
+		Aggregation(FacetTags, tagsFacet).
+		Aggregation(FacetMonths, monthsFacet).
+		Highlight(highlight).
+		Sort("_score", false).
+		Sort("id", false).
+		TrackTotalHits(true).
+		From(search.From).
+		Size(search.Size)
+	if len(search.SearchAfter) > 0 {
+		service = service.SearchAfter(search.SearchAfter...)
+	}
+
+	return service.Do(ctx)
+}
+
+// spellingSuggester corrects misspelled query words against the terms of the
+// exact (accented) subfields, so "bai vet" can become "bài viết". The collate
+// query keeps only corrections that match a post the caller may read.
+func spellingSuggester(query string, visibility PostVisibility) (*elastic.PhraseSuggester, error) {
+	collate := elastic.NewBoolQuery().Must(
+		elastic.NewMultiMatchQuery("{{suggestion}}", "title", "content").Operator("and"),
+	)
+	if filter := visibilityFilter(visibility); filter != nil {
+		collate = collate.Filter(filter)
+	}
+	source, err := collate.Source()
+	if err != nil {
+		return nil, err
+	}
+	template, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return elastic.NewPhraseSuggester(SearchDidYouMean).
+		Text(query).
+		Field("content.exact").
+		Size(1).
+		MaxErrors(2).
+		CandidateGenerators(
+			elastic.NewDirectCandidateGenerator("title.exact").SuggestMode("always").MinWordLength(2),
+			elastic.NewDirectCandidateGenerator("content.exact").SuggestMode("always").MinWordLength(2),
+		).
+		CollateQuery(elastic.NewScriptInline(string(template))), nil
+}
+
+// Strategies of FindRelatedPosts
+const (
+	// RelatedByTags ranks posts by the number of tags they share
+	RelatedByTags = "tags"
+	// RelatedByContent ranks posts by the terms their title and content share
+	RelatedByContent = "content"
+	// RelatedHybrid combines both and favours recent posts
+	RelatedHybrid = "hybrid"
+)
+
+// FindRelatedPosts finds posts similar to the post with the given ID and tags
+// using one of the Related* strategies
+func FindRelatedPosts(strategy string, postID uint, tags []string, limit int, visibility PostVisibility) (*elastic.SearchResult, error) {
+	if esClient == nil {
+		return nil, ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+
+	boolQuery := elastic.NewBoolQuery()
+
+	// One should clause per tag, so every shared tag adds to the score
+	if strategy == RelatedByTags || strategy == RelatedHybrid {
+		for _, tag := range tags {
+			boolQuery = boolQuery.Should(elastic.NewTermQuery("tags", tag))
+		}
+	}
+
+	// more_like_this picks the most distinctive terms of the post's own
+	// document, which ES fetches from the index
+	if strategy == RelatedByContent || strategy == RelatedHybrid {
+		boolQuery = boolQuery.Should(elastic.NewMoreLikeThisQuery().
+			Field("title", "content").
+			LikeItems(elastic.NewMoreLikeThisQueryItem().Index(postsAlias).Id(fmt.Sprintf("%d", postID))).
+			MinTermFreq(1).
+			MinDocFreq(2).
+			MaxQueryTerms(25))
+	}
+
+	// Exclude the current post and require at least one match
+	boolQuery = boolQuery.
+		MustNot(elastic.NewTermQuery("id", postID)).
+		MinimumShouldMatch("1")
+
+	// Hide posts the caller may not read
+	if filter := visibilityFilter(visibility); filter != nil {
+		boolQuery = boolQuery.Filter(filter)
+	}
+
+	var query elastic.Query = boolQuery
+	if strategy == RelatedHybrid {
+		// Posts older than a week lose half their score every month or so
+		recency := elastic.NewGaussDecayFunction().
+			FieldName("created_at").
+			Origin("now").
+			Offset("7d").
+			Scale("30d").
+			Decay(0.5)
+		query = elastic.NewFunctionScoreQuery().
+			Query(boolQuery).
+			AddScoreFunc(recency).
+			BoostMode("multiply")
+	}
+
+	return esClient.Search().
+		Index(postsAlias).
+		Query(query).
+		Sort("_score", false).
+		From(0).
+		Size(limit).
+		Do(ctx)
+}
+
+// Names of the title completion suggester and tag aggregation of SuggestPosts
+const (
+	SuggestTitles = "suggest_titles"
+	SuggestTags   = "suggest_tags"
+)
+
+// PostSuggestContexts returns the completion contexts a post is indexed with
+func PostSuggestContexts(status string, authorID *uint) map[string][]string {
+	values := []string{"status:" + status}
+	if authorID != nil {
+		values = append(values, fmt.Sprintf("author:%d", *authorID))
+	}
+	return map[string][]string{suggestContext: values}
+}
+
+// suggestContextQuery limits completions to the posts allowed by v, or
+// returns nil when every post is visible
+func suggestContextQuery(v PostVisibility) elastic.SuggesterContextQuery {
+	if v.IncludeAllDrafts {
+		return nil
+	}
+
+	values := []string{"status:" + models.PostStatusPublished}
+	if v.DraftsByAuthorID != nil {
+		values = append(values, fmt.Sprintf("author:%d", *v.DraftsByAuthorID))
+	}
+	return elastic.NewSuggesterCategoryQuery(suggestContext, values...)
+}
+
+// SuggestPosts completes a title prefix and finds tags starting with it in a
+// single request: a completion suggester on title_suggest and a terms
+// aggregation over the visible posts. No hits are fetched.
+func SuggestPosts(prefix string, size int, visibility PostVisibility) (*elastic.SearchResult, error) {
+	if esClient == nil {
+		return nil, ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+
+	titles := elastic.NewCompletionSuggester(SuggestTitles).
+		Field("title_suggest").
+		Prefix(prefix).
+		SkipDuplicates(true).
+		Size(size)
+	if contexts := suggestContextQuery(visibility); contexts != nil {
+		titles = titles.ContextQuery(contexts)
+	}
+
+	query := elastic.NewBoolQuery()
+	if filter := visibilityFilter(visibility); filter != nil {
+		query = query.Filter(filter)
+	}
+	tags := elastic.NewTermsAggregation().
+		Field("tags").
+		Include(caseInsensitivePrefix(prefix)).
+		Size(size)
+
+	return esClient.Search().
+		Index(postsAlias).
+		Query(query).
+		Suggester(titles).
+		Aggregation(SuggestTags, tags).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("id", "title", "slug")).
+		Size(0).
+		Do(ctx)
+}
+
+// caseInsensitivePrefix builds a Lucene regular expression matching values
+// that start with prefix in any letter case
+func caseInsensitivePrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		lower, upper := unicode.ToLower(r), unicode.ToUpper(r)
+		switch {
+		case lower != upper:
+			b.WriteString("[" + string(lower) + string(upper) + "]")
+		case strings.ContainsRune(`.?+*|{}[]()"\#@&<>~`, r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(".*")
+	return b.String()
 }
\ No newline at end of file
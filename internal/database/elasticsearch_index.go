@@ -0,0 +1,295 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// The posts index is never addressed by its physical name. Searches use the
+// read alias, indexing uses the write alias, and both point at the current
+// versioned index so a reindex can build a new one and swap them atomically.
+var (
+	postsAlias      = "posts"
+	postsWriteAlias = "posts_write"
+	postsIndexBody  string
+)
+
+// PostsWriteAlias returns the alias documents are indexed through
+func PostsWriteAlias() string {
+	return postsWriteAlias
+}
+
+// aliasTargets returns the indices an alias points at, nil if it doesn't exist
+func aliasTargets(ctx context.Context, client *elastic.Client, alias string) ([]string, error) {
+	result, err := client.Aliases().Alias(alias).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.IndicesByAlias(alias), nil
+}
+
+// ensurePostsIndex makes sure both aliases resolve. A fresh cluster gets
+// <alias>_v1. A concrete index named like the alias, created before aliases
+// were used, keeps serving reads and gets the write alias until the first
+// reindex replaces it.
+func ensurePostsIndex(ctx context.Context, client *elastic.Client) error {
+	targets, err := aliasTargets(ctx, client, postsAlias)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 0 {
+		legacy, err := client.IndexExists(postsAlias).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if !legacy {
+			index, err := createPostsIndex(ctx, client)
+			if err != nil {
+				return err
+			}
+			_, err = client.Alias().
+				Add(index, postsAlias).
+				Add(index, postsWriteAlias).
+				Do(ctx)
+			return err
+		}
+
+		log.Printf("Elasticsearch index %s is not versioned yet; run the reindex command to move it behind aliases", postsAlias)
+		targets = []string{postsAlias}
+	} else {
+		version, err := indexMappingVersion(ctx, client, targets[0])
+		if err != nil {
+			return err
+		}
+		if version < postsMappingVersion {
+			log.Printf("Elasticsearch index %s has mapping version %d, current is %d; run the reindex command", targets[0], version, postsMappingVersion)
+		}
+	}
+
+	writeTargets, err := aliasTargets(ctx, client, postsWriteAlias)
+	if err != nil {
+		return err
+	}
+	if len(writeTargets) == 0 {
+		_, err = client.Alias().Add(targets[0], postsWriteAlias).Do(ctx)
+		return err
+	}
+	return nil
+}
+
+// postsIndexVersions returns the versions of the existing <alias>_vN indices
+func postsIndexVersions(ctx context.Context, client *elastic.Client) ([]int, error) {
+	names, err := client.IndexNames()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := postsAlias + "_v"
+	var versions []int
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.TrimPrefix(name, prefix)); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// CreatePostsIndex creates the next versioned posts index with the current
+// mapping and returns its name. No alias points at it yet.
+func CreatePostsIndex(ctx context.Context) (string, error) {
+	if esClient == nil {
+		return "", ErrElasticsearchUnavailable
+	}
+	return createPostsIndex(ctx, esClient)
+}
+
+func createPostsIndex(ctx context.Context, client *elastic.Client) (string, error) {
+	versions, err := postsIndexVersions(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+
+	index := fmt.Sprintf("%s_v%d", postsAlias, next)
+	if _, err := client.CreateIndex(index).BodyString(postsIndexBody).Do(ctx); err != nil {
+		return "", err
+	}
+	log.Printf("Created Elasticsearch index %s", index)
+	return index, nil
+}
+
+// BulkIndexPosts writes documents keyed by post ID into index
+func BulkIndexPosts(ctx context.Context, index string, docs map[string]interface{}) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	bulk := esClient.Bulk().Index(index)
+	for id, doc := range docs {
+		bulk = bulk.Add(elastic.NewBulkIndexRequest().Id(id).Doc(doc))
+	}
+	return doBulk(ctx, bulk)
+}
+
+// BulkDeletePosts removes documents by post ID from index. Missing documents
+// are not an error.
+func BulkDeletePosts(ctx context.Context, index string, ids []string) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	bulk := esClient.Bulk().Index(index)
+	for _, id := range ids {
+		bulk = bulk.Add(elastic.NewBulkDeleteRequest().Id(id))
+	}
+	return doBulk(ctx, bulk)
+}
+
+func doBulk(ctx context.Context, bulk *elastic.BulkService) error {
+	response, err := bulk.Do(ctx)
+	if err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+
+	var failed []string
+	for _, item := range response.Failed() {
+		if item.Status == 404 {
+			continue
+		}
+		reason := ""
+		if item.Error != nil {
+			reason = item.Error.Reason
+		}
+		failed = append(failed, fmt.Sprintf("%s: %s", item.Id, reason))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("bulk request failed for %d documents: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// SwapPostsAliases points both aliases at index in one atomic request and
+// returns the indices they pointed at before. A legacy concrete index named
+// like the alias is deleted in the same request, since the alias cannot be
+// created while it exists.
+func SwapPostsAliases(ctx context.Context, index string) ([]string, error) {
+	if esClient == nil {
+		return nil, ErrElasticsearchUnavailable
+	}
+
+	previous, err := aliasTargets(ctx, esClient, postsAlias)
+	if err != nil {
+		return nil, err
+	}
+	writeTargets, err := aliasTargets(ctx, esClient, postsWriteAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	swap := esClient.Alias()
+	for _, old := range previous {
+		swap = swap.Remove(old, postsAlias)
+	}
+	for _, old := range writeTargets {
+		if old != postsAlias {
+			swap = swap.Remove(old, postsWriteAlias)
+		}
+	}
+
+	if len(previous) == 0 {
+		legacy, err := esClient.IndexExists(postsAlias).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if legacy {
+			swap = swap.Action(elastic.NewAliasRemoveIndexAction(postsAlias))
+		}
+	}
+
+	if _, err := swap.Add(index, postsAlias).Add(index, postsWriteAlias).Do(ctx); err != nil {
+		return nil, err
+	}
+	log.Printf("Aliases %s and %s now point at %s", postsAlias, postsWriteAlias, index)
+	return previous, nil
+}
+
+// DeletePostsIndex drops a versioned posts index that no alias points at
+func DeletePostsIndex(ctx context.Context, index string) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	_, err := esClient.DeleteIndex(index).Do(ctx)
+	return err
+}
+
+// ScanIndexedPosts returns the updated_at of every indexed post keyed by post
+// ID. Documents indexed before updated_at was stored map to the zero time.
+func ScanIndexedPosts(ctx context.Context) (map[uint]time.Time, error) {
+	if esClient == nil {
+		return nil, ErrElasticsearchUnavailable
+	}
+
+	scroll := esClient.Scroll(postsAlias).
+		Size(1000).
+		KeepAlive("1m").
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("updated_at"))
+	defer scroll.Clear(context.Background())
+
+	indexed := make(map[uint]time.Time)
+	for {
+		result, err := scroll.Do(ctx)
+		if err == io.EOF {
+			return indexed, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range result.Hits.Hits {
+			id, err := strconv.ParseUint(hit.Id, 10, 32)
+			if err != nil {
+				log.Printf("Skipping document with non-numeric id %q", hit.Id)
+				continue
+			}
+
+			var doc struct {
+				UpdatedAt time.Time `json:"updated_at"`
+			}
+			if hit.Source != nil {
+				if err := json.Unmarshal(hit.Source, &doc); err != nil {
+					return nil, err
+				}
+			}
+			indexed[uint(id)] = doc.UpdatedAt
+		}
+	}
+}
\ No newline at end of file
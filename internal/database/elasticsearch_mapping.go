@@ -0,0 +1,182 @@
+package database
+
+import (
+	"blog-api/internal/config"
+	"context"
+	"encoding/json"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// postsMappingVersion is stored in the index _meta. Bump it whenever the
+// settings or mapping below change; the server then warns until the posts
+// are reindexed into a new index with the reindex command.
+const postsMappingVersion = 3
+
+// Analyzers of the posts index. Title and content are indexed folded, so
+// "bai viet" matches "bài viết", with an exact subfield that keeps the
+// diacritics and ranks accented matches higher.
+const (
+	analyzerFolded  = "vi_folded"
+	analyzerSearch  = "vi_folded_search"
+	analyzerExact   = "vi_exact"
+	analyzerSuggest = "vi_suggest"
+)
+
+// suggestContext is the category context of title completions. Each document
+// is tagged "status:<status>" and "author:<id>" so suggestions can be limited
+// to what the caller may read; see PostSuggestContexts.
+const suggestContext = "visibility"
+
+// buildPostsIndexBody builds the settings and mapping of the posts index
+func buildPostsIndexBody(cfg *config.ElasticsearchConfig) (string, error) {
+	data, err := json.Marshal(postsIndexDefinition(cfg))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// buildPercolatorIndexBody builds the saved search percolator index. Posts are
+// percolated with the analysis and field mappings of the posts index; other
+// fields of the document are ignored.
+func buildPercolatorIndexBody(cfg *config.ElasticsearchConfig) (string, error) {
+	body := postsIndexDefinition(cfg)
+	mappings := body["mappings"].(map[string]interface{})
+	properties := mappings["properties"].(map[string]interface{})
+
+	delete(mappings, "_meta")
+	delete(properties, "title_suggest")
+	mappings["dynamic"] = false
+	properties["query"] = map[string]interface{}{"type": "percolator"}
+	properties["saved_search_id"] = map[string]interface{}{"type": "integer"}
+	properties["user_id"] = map[string]interface{}{"type": "integer"}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// postsIndexDefinition returns a fresh copy of the posts index settings and
+// mapping
+func postsIndexDefinition(cfg *config.ElasticsearchConfig) map[string]interface{} {
+	tokenizer := "standard"
+	folding := []string{"lowercase", "asciifolding"}
+	if cfg.UseICU {
+		tokenizer = "icu_tokenizer"
+		folding = []string{"icu_folding"}
+	}
+
+	filters := map[string]interface{}{}
+	foldedFilters := append([]string{}, folding...)
+	if len(cfg.Stopwords) > 0 {
+		filters["vi_stop"] = map[string]interface{}{
+			"type":      "stop",
+			"stopwords": cfg.Stopwords,
+		}
+		foldedFilters = append(foldedFilters, "vi_stop")
+	}
+
+	analyzers := map[string]interface{}{
+		analyzerFolded: map[string]interface{}{
+			"type":      "custom",
+			"tokenizer": tokenizer,
+			"filter":    foldedFilters,
+		},
+		analyzerExact: map[string]interface{}{
+			"type":      "custom",
+			"tokenizer": tokenizer,
+			"filter":    []string{"lowercase"},
+		},
+		// Completions fold diacritics but keep stopwords, which a prefix may be
+		analyzerSuggest: map[string]interface{}{
+			"type":      "custom",
+			"tokenizer": tokenizer,
+			"filter":    folding,
+		},
+	}
+
+	// Synonyms are expanded at search time only, so editing the file needs
+	// no reindex, just a reload of the search analyzers
+	searchAnalyzer := analyzerFolded
+	if cfg.SynonymsPath != "" {
+		filters["vi_synonyms"] = map[string]interface{}{
+			"type":          "synonym_graph",
+			"synonyms_path": cfg.SynonymsPath,
+			"updateable":    true,
+		}
+		analyzers[analyzerSearch] = map[string]interface{}{
+			"type":      "custom",
+			"tokenizer": tokenizer,
+			"filter":    append(append([]string{}, foldedFilters...), "vi_synonyms"),
+		}
+		searchAnalyzer = analyzerSearch
+	}
+
+	textField := map[string]interface{}{
+		"type":            "text",
+		"analyzer":        analyzerFolded,
+		"search_analyzer": searchAnalyzer,
+		"fields": map[string]interface{}{
+			"exact": map[string]interface{}{
+				"type":     "text",
+				"analyzer": analyzerExact,
+			},
+		},
+	}
+
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"analysis": map[string]interface{}{
+				"filter":   filters,
+				"analyzer": analyzers,
+			},
+		},
+		"mappings": map[string]interface{}{
+			"_meta": map[string]interface{}{
+				"mapping_version": postsMappingVersion,
+			},
+			"properties": map[string]interface{}{
+				"id":           map[string]interface{}{"type": "integer"},
+				"title":        textField,
+				"slug":         map[string]interface{}{"type": "keyword"},
+				"content":      textField,
+				"tags":         map[string]interface{}{"type": "keyword"},
+				"author_id":    map[string]interface{}{"type": "integer"},
+				"status":       map[string]interface{}{"type": "keyword"},
+				"published_at": map[string]interface{}{"type": "date"},
+				"created_at":   map[string]interface{}{"type": "date"},
+				"updated_at":   map[string]interface{}{"type": "date"},
+				"title_suggest": map[string]interface{}{
+					"type":     "completion",
+					"analyzer": analyzerSuggest,
+					"contexts": []map[string]interface{}{
+						{"name": suggestContext, "type": "category"},
+					},
+				},
+			},
+		},
+	}
+	return body
+}
+
+// indexMappingVersion reads the mapping_version from an index's _meta, 0 when
+// the index predates versioned mappings
+func indexMappingVersion(ctx context.Context, client *elastic.Client, index string) (int, error) {
+	mappings, err := client.GetMapping().Index(index).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range mappings {
+		definition, _ := entry.(map[string]interface{})
+		mapping, _ := definition["mappings"].(map[string]interface{})
+		meta, _ := mapping["_meta"].(map[string]interface{})
+		if version, ok := meta["mapping_version"].(float64); ok {
+			return int(version), nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// The percolator index stores the query of every saved search, keyed by the
+// saved search ID, so a newly published post is matched against all of them
+// in one request. Its mapping is copied from the posts index when it is
+// created and is not versioned: after changing the posts mapping, delete it
+// and run the reindex command, which registers every saved search again.
+var (
+	percolatorIndex     = "posts_percolator"
+	percolatorIndexBody string
+)
+
+// percolatorPageSize is the number of matching saved searches fetched per
+// request while percolating a post
+const percolatorPageSize = 500
+
+// ensurePercolatorIndex creates the percolator index if it doesn't exist
+func ensurePercolatorIndex(ctx context.Context, client *elastic.Client) error {
+	exists, err := client.IndexExists(percolatorIndex).Do(ctx)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := client.CreateIndex(percolatorIndex).BodyString(percolatorIndexBody).Do(ctx); err != nil {
+		return err
+	}
+	log.Printf("Created Elasticsearch index %s", percolatorIndex)
+	return nil
+}
+
+// savedSearchQuery matches posts containing every word of query across title
+// and content, with diacritics folded as in search, and all of tags
+func savedSearchQuery(query string, tags []string) elastic.Query {
+	boolQuery := elastic.NewBoolQuery().Must(
+		elastic.NewMultiMatchQuery(query, "title", "content").
+			Type("cross_fields").
+			Operator("and"),
+	)
+	for _, tag := range tags {
+		boolQuery = boolQuery.Filter(elastic.NewTermQuery("tags", tag))
+	}
+	return boolQuery
+}
+
+// RegisterSavedSearch stores the query of a saved search in the percolator
+func RegisterSavedSearch(id, userID uint, query string, tags []string) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	source, err := savedSearchQuery(query, tags).Source()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = esClient.Index().
+		Index(percolatorIndex).
+		Id(fmt.Sprintf("%d", id)).
+		BodyJson(map[string]interface{}{
+			"query":           source,
+			"saved_search_id": id,
+			"user_id":         userID,
+		}).
+		Do(ctx)
+	return err
+}
+
+// UnregisterSavedSearch removes the query of a saved search from the
+// percolator
+func UnregisterSavedSearch(id uint) error {
+	if esClient == nil {
+		return ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+	_, err := esClient.Delete().
+		Index(percolatorIndex).
+		Id(fmt.Sprintf("%d", id)).
+		Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// PercolatePost returns the IDs of the saved searches whose query matches doc
+func PercolatePost(doc interface{}) ([]uint, error) {
+	if esClient == nil {
+		return nil, ErrElasticsearchUnavailable
+	}
+	ctx := context.Background()
+
+	query := elastic.NewPercolatorQuery().Field("query").Document(doc)
+	var ids []uint
+	var after []interface{}
+	for {
+		service := esClient.Search().
+			Index(percolatorIndex).
+			Query(query).
+			FetchSource(false).
+			Sort("saved_search_id", true).
+			Size(percolatorPageSize)
+		if after != nil {
+			service = service.SearchAfter(after...)
+		}
+		result, err := service.Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := result.Hits.Hits
+		for _, hit := range hits {
+			id, err := strconv.ParseUint(hit.Id, 10, 32)
+			if err != nil {
+				log.Printf("Skipping saved search with non-numeric id %q", hit.Id)
+				continue
+			}
+			ids = append(ids, uint(id))
+		}
+		if len(hits) < percolatorPageSize {
+			return ids, nil
+		}
+		after = hits[len(hits)-1].Sort
+	}
+}
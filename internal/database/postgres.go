@@ -1,44 +1,80 @@
-package database
-
-import (
-	"blog-api/internal/config"
-	"blog-api/internal/models"
-	"log"
-
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-)
-
-func ConnectPostgres(cfg *config.DatabaseConfig) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	log.Println("Connected to PostgreSQL successfully")
-
-	// Auto migrate tables
-	err = db.AutoMigrate(&models.Post{}, &models.ActivityLog{})
-	if err != nil {
-		log.Printf("Error migrating tables: %v", err)
-		return nil, err
-	}
-
-	log.Println("Tables migrated successfully")
-	return db, nil
-}
-
-func GetDB() *gorm.DB {
-	return db
-}
-
-var db *gorm.DB
-
-func InitDB(cfg *config.DatabaseConfig) error {
-	var err error
-	db, err = ConnectPostgres(cfg)
-	return err
+package database
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/models"
+	"log"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func ConnectPostgres(cfg *config.DatabaseConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Connected to PostgreSQL successfully")
+
+	// Auto migrate tables
+	err = db.AutoMigrate(&models.Post{}, &models.ActivityLog{}, &models.OutboxEvent{}, &models.PostRevision{}, &models.User{}, &models.PostSlugRedirect{}, &models.SearchLog{}, &models.SavedSearch{}, &models.SearchNotification{})
+	if err != nil {
+		log.Printf("Error migrating tables: %v", err)
+		return nil, err
+	}
+
+	if err := applySchemaChanges(db); err != nil {
+		log.Printf("Error applying schema changes: %v", err)
+		return nil, err
+	}
+
+	log.Println("Tables migrated successfully")
+	return db, nil
+}
+
+// schemaChanges are idempotent statements for changes AutoMigrate cannot make.
+// Databases initialized from migrations/ need them as well, since the init
+// scripts only run against an empty volume.
+var schemaChanges = []string{
+	// Keep the audit trail of deleted posts (was ON DELETE CASCADE)
+	`ALTER TABLE activity_logs DROP CONSTRAINT IF EXISTS activity_logs_post_id_fkey`,
+
+	// Post listing filters and keyset sorts (see migrations/010)
+	`CREATE INDEX IF NOT EXISTS idx_posts_tags ON posts USING GIN (tags)`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_updated_at_id ON posts (updated_at DESC, id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_published_at_id ON posts (published_at DESC NULLS LAST, id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_title_id ON posts (title, id)`,
+
+	// Full-text search fallback when Elasticsearch is unavailable (see migrations/011)
+	`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`,
+}
+
+func applySchemaChanges(db *gorm.DB) error {
+	for _, statement := range schemaChanges {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetDB() *gorm.DB {
+	return db
+}
+
+var db *gorm.DB
+
+func InitDB(cfg *config.DatabaseConfig) error {
+	var err error
+	db, err = ConnectPostgres(cfg)
+	return err
 }
\ No newline at end of file
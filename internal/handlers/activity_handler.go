@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"blog-api/internal/models"
+	"blog-api/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ActivityHandler struct {
+	activityService *services.ActivityService
+}
+
+func NewActivityHandler() *ActivityHandler {
+	return &ActivityHandler{
+		activityService: services.NewActivityService(),
+	}
+}
+
+// ListActivity handles GET /activity?post_id=&action=&actor_id=&from=&to=
+func (ah *ActivityHandler) ListActivity(c *gin.Context) {
+	filter := models.ActivityFilter{
+		Action: c.Query("action"),
+	}
+
+	if value := c.Query("post_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post_id"})
+			return
+		}
+		postID := uint(id)
+		filter.PostID = &postID
+	}
+
+	if value := c.Query("actor_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid actor_id"})
+			return
+		}
+		userID := uint(id)
+		filter.UserID = &userID
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'from' must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.From = &from
+	}
+
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'to' must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.To = &to
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	filter.Limit = limit
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	filter.Offset = offset
+
+	logs, err := ah.activityService.ListActivity(actorFrom(c), &filter)
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   logs,
+		"limit":  filter.Limit,
+		"offset": offset,
+	})
+}
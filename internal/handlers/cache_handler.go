@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"blog-api/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CacheHandler struct {
+	cacheService *services.CacheService
+}
+
+func NewCacheHandler() *CacheHandler {
+	return &CacheHandler{
+		cacheService: services.NewCacheService(),
+	}
+}
+
+// Stats handles GET /cache/stats, reporting this instance's post cache hits
+// and misses per layer
+func (ch *CacheHandler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": ch.cacheService.Stats()})
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"blog-api/internal/middleware"
+	"blog-api/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// actorFrom builds the service-layer actor for the current request
+func actorFrom(c *gin.Context) services.Actor {
+	return services.Actor{
+		User:      middleware.CurrentUser(c),
+		RequestID: middleware.GetRequestID(c),
+	}
+}
+
+// respondForbidden writes a 403 with the reason if err is a permission error
+func respondForbidden(c *gin.Context, err error) bool {
+	var permissionErr *services.PermissionError
+	if !errors.As(err, &permissionErr) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": permissionErr.Reason})
+	return true
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OutboxHandler struct {
+	outboxService *services.OutboxService
+}
+
+func NewOutboxHandler(cfg *config.OutboxConfig) *OutboxHandler {
+	return &OutboxHandler{
+		outboxService: services.NewOutboxService(cfg),
+	}
+}
+
+// ListEvents handles GET /outbox?status=<pending|delivered|dead>
+func (oh *OutboxHandler) ListEvents(c *gin.Context) {
+	status := c.Query("status")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	events, err := oh.outboxService.ListEvents(status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   events,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RetryEvent handles POST /outbox/:id/retry
+func (oh *OutboxHandler) RetryEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	event, err := oh.outboxService.RetryEvent(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Outbox event not found"})
+		case errors.Is(err, services.ErrOutboxEventNotDead):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Outbox event queued for retry",
+		"data":    event,
+	})
+}
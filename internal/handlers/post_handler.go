@@ -1,169 +1,420 @@
-package handlers
-
-import (
-	"blog-api/internal/models"
-	"blog-api/internal/services"
-	"net/http"
-	"strconv"
-
-	"github.com/gin-gonic/gin"
-)
-
-type PostHandler struct {
-	postService *services.PostService
-}
-
-func NewPostHandler() *PostHandler {
-	return &PostHandler{
-		postService: services.NewPostService(),
-	}
-}
-
-// CreatePost handles POST /posts
-func (ph *PostHandler) CreatePost(c *gin.Context) {
-	var req models.CreatePostRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	post, err := ph.postService.CreatePost(&req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Post created successfully",
-		"data":    post,
-	})
-}
-
-// GetPost handles GET /posts/:id
-func (ph *PostHandler) GetPost(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.ParseUint(idParam, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-		return
-	}
-
-	post, err := ph.postService.GetPostByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"data": post})
-}
-
-// UpdatePost handles PUT /posts/:id
-func (ph *PostHandler) UpdatePost(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.ParseUint(idParam, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-		return
-	}
-
-	var req models.UpdatePostRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	post, err := ph.postService.UpdatePost(uint(id), &req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Post updated successfully",
-		"data":    post,
-	})
-}
-
-// DeletePost handles DELETE /posts/:id
-func (ph *PostHandler) DeletePost(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.ParseUint(idParam, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
-		return
-	}
-
-	err = ph.postService.DeletePost(uint(id))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
-}
-
-// SearchPostsByTag handles GET /posts/search-by-tag?tag=<tag_name>
-func (ph *PostHandler) SearchPostsByTag(c *gin.Context) {
-	tag := c.Query("tag")
-	if tag == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag parameter is required"})
-		return
-	}
-
-	posts, err := ph.postService.SearchPostsByTag(tag)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data":  posts,
-		"total": len(posts),
-	})
-}
-
-// SearchPosts handles GET /posts/search?q=<query_string>
-func (ph *PostHandler) SearchPosts(c *gin.Context) {
-	query := c.Query("q")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter is required"})
-		return
-	}
-
-	result, err := ph.postService.SearchPosts(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"data": result})
-}
-
-// GetAllPosts handles GET /posts
-func (ph *PostHandler) GetAllPosts(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
-	}
-
-	posts, err := ph.postService.GetAllPosts(limit, offset)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data":   posts,
-		"total":  len(posts),
-		"limit":  limit,
-		"offset": offset,
-	})
+package handlers
+
+import (
+	"blog-api/internal/models"
+	"blog-api/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PostHandler struct {
+	postService *services.PostService
+}
+
+func NewPostHandler() *PostHandler {
+	return &PostHandler{
+		postService: services.NewPostService(),
+	}
+}
+
+// CreatePost handles POST /posts
+func (ph *PostHandler) CreatePost(c *gin.Context) {
+	var req models.CreatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	post, err := ph.postService.CreatePost(actorFrom(c), &req)
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		if services.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrSlugConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Post created successfully",
+		"data":    post,
+	})
+}
+
+// GetPost handles GET /posts/:id?related=tags|content|hybrid
+func (ph *PostHandler) GetPost(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	post, err := ph.postService.GetPostByID(actorFrom(c), uint(id), c.Query("related"))
+	if err != nil {
+		if services.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": post})
+}
+
+// GetPostBySlug handles GET /posts/by-slug/:slug?related=tags|content|hybrid
+func (ph *PostHandler) GetPostBySlug(c *gin.Context) {
+	post, currentSlug, err := ph.postService.GetPostBySlug(actorFrom(c), c.Param("slug"), c.Query("related"))
+	if err != nil {
+		if services.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Old slugs permanently redirect to the current one
+	if currentSlug != "" {
+		c.Redirect(http.StatusMovedPermanently, "/api/v1/posts/by-slug/"+url.PathEscape(currentSlug))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": post})
+}
+
+// UpdatePost handles PUT /posts/:id
+func (ph *PostHandler) UpdatePost(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var req models.UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	post, err := ph.postService.UpdatePost(actorFrom(c), uint(id), &req)
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, services.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		if services.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrSlugConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post updated successfully",
+		"data":    post,
+	})
+}
+
+// DeletePost handles DELETE /posts/:id
+func (ph *PostHandler) DeletePost(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	err = ph.postService.DeletePost(actorFrom(c), uint(id))
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, services.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
+}
+
+// SearchPostsByTag handles GET /posts/search-by-tag?tag=<tag_name>
+func (ph *PostHandler) SearchPostsByTag(c *gin.Context) {
+	tag := c.Query("tag")
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag parameter is required"})
+		return
+	}
+
+	posts, err := ph.postService.SearchPostsByTag(actorFrom(c), tag)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  posts,
+		"total": len(posts),
+	})
+}
+
+// SearchPosts handles GET /posts/search?q=<query_string>&tag=&from=&to=&page=&size=&search_after=&autocorrect=
+func (ph *PostHandler) SearchPosts(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter is required"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	if err != nil || size <= 0 {
+		size = 10
+	}
+
+	search := &models.SearchQuery{
+		Query:       query,
+		Page:        page,
+		Size:        size,
+		SearchAfter: c.Query("search_after"),
+	}
+
+	// Facet selections: tag may repeat, from/to bound the creation date
+	for _, tag := range c.QueryArray("tag") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			search.Tags = append(search.Tags, tag)
+		}
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'from' must be an RFC 3339 timestamp"})
+			return
+		}
+		search.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'to' must be an RFC 3339 timestamp"})
+			return
+		}
+		search.To = &to
+	}
+	if value := c.Query("autocorrect"); value != "" {
+		autocorrect, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'autocorrect' must be a boolean"})
+			return
+		}
+		search.Autocorrect = autocorrect
+	}
+
+	result, err := ph.postService.SearchPosts(actorFrom(c), search)
+	if err != nil {
+		if services.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// RecordSearchClick handles POST /posts/search/clicks
+func (ph *PostHandler) RecordSearchClick(c *gin.Context) {
+	var req models.SearchClickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ph.postService.RecordSearchClick(&req); err != nil {
+		if errors.Is(err, services.ErrSearchLogNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Search not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Click recorded"})
+}
+
+// SuggestPosts handles GET /posts/suggest?q=<prefix>&size=
+func (ph *PostHandler) SuggestPosts(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter is required"})
+		return
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", "5"))
+	if err != nil || size <= 0 {
+		size = 5
+	}
+
+	result, err := ph.postService.SuggestPosts(actorFrom(c), prefix, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// GetAllPosts handles GET /posts?tags=&tag_mode=&author_id=&status=&created_from=&created_to=&updated_from=&updated_to=&sort=&order=
+func (ph *PostHandler) GetAllPosts(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "10")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	page := &models.PostListQuery{
+		TagMode: c.Query("tag_mode"),
+		Status:  c.Query("status"),
+		Sort:    c.Query("sort"),
+		Order:   c.Query("order"),
+		Limit:   limit,
+		Offset:  offset,
+		Cursor:  c.Query("cursor"),
+	}
+
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			page.Tags = append(page.Tags, tag)
+		}
+	}
+
+	if value := c.Query("author_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author_id"})
+			return
+		}
+		authorID := uint(id)
+		page.AuthorID = &authorID
+	}
+
+	ranges := []struct {
+		param  string
+		target **time.Time
+	}{
+		{"created_from", &page.CreatedFrom},
+		{"created_to", &page.CreatedTo},
+		{"updated_from", &page.UpdatedFrom},
+		{"updated_to", &page.UpdatedTo},
+	}
+	for _, r := range ranges {
+		value := c.Query(r.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Parameter '%s' must be an RFC 3339 timestamp", r.param)})
+			return
+		}
+		*r.target = &t
+	}
+
+	result, err := ph.postService.GetAllPosts(actorFrom(c), page)
+	if err != nil {
+		if services.IsValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if link := paginationLinks(c, result); link != "" {
+		c.Header("Link", link)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":            result.Posts,
+		"total":           result.Total,
+		"total_estimated": result.TotalEstimated,
+		"limit":           page.Limit,
+		"offset":          page.Offset,
+		"sort":            page.Sort,
+		"order":           page.Order,
+		"next_cursor":     result.NextCursor,
+		"prev_cursor":     result.PrevCursor,
+	})
+}
+
+// paginationLinks builds an RFC 8288 Link header pointing at the neighbouring
+// pages, keeping every other query parameter of the current request
+func paginationLinks(c *gin.Context, result *models.PostListResult) string {
+	pageURL := func(cursor string) string {
+		query := c.Request.URL.Query()
+		query.Del("offset")
+		query.Del("cursor")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		u := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+		return u.String()
+	}
+
+	var links []string
+	if result.NextCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(result.NextCursor)))
+	}
+	if result.PrevCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(result.PrevCursor)))
+		links = append(links, fmt.Sprintf(`<%s>; rel="first"`, pageURL("")))
+	}
+	return strings.Join(links, ", ")
 }
\ No newline at end of file
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"blog-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RevisionHandler struct {
+	revisionService *services.RevisionService
+}
+
+func NewRevisionHandler() *RevisionHandler {
+	return &RevisionHandler{
+		revisionService: services.NewRevisionService(),
+	}
+}
+
+// ListRevisions handles GET /posts/:id/revisions
+func (rh *RevisionHandler) ListRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	revisions, err := rh.revisionService.ListRevisions(actorFrom(c), uint(id))
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  revisions,
+		"total": len(revisions),
+	})
+}
+
+// GetRevision handles GET /posts/:id/revisions/:version
+func (rh *RevisionHandler) GetRevision(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision version"})
+		return
+	}
+
+	revision, err := rh.revisionService.GetRevision(actorFrom(c), uint(id), version)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": revision})
+}
+
+// DiffRevisions handles GET /posts/:id/revisions/diff?from=<version>&to=<version>&mode=<line|word>
+func (rh *RevisionHandler) DiffRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'from' must be a revision version"})
+		return
+	}
+
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil || to <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'to' must be a revision version"})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", services.DiffModeLine)
+	if mode != services.DiffModeLine && mode != services.DiffModeWord {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'mode' must be 'line' or 'word'"})
+		return
+	}
+
+	diff, err := rh.revisionService.DiffRevisions(actorFrom(c), uint(id), from, to, mode)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+// RestoreRevision handles POST /posts/:id/revisions/:version/restore
+func (rh *RevisionHandler) RestoreRevision(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision version"})
+		return
+	}
+
+	post, err := rh.revisionService.RestoreRevision(actorFrom(c), uint(id), version)
+	if err != nil {
+		respondRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Revision restored successfully",
+		"data":    post,
+	})
+}
+
+func respondRevisionError(c *gin.Context, err error) {
+	if respondForbidden(c, err) {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, services.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post or revision not found"})
+		return
+	}
+	if errors.Is(err, services.ErrSlugConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
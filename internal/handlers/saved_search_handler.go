@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"blog-api/internal/models"
+	"blog-api/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SavedSearchHandler struct {
+	savedSearchService *services.SavedSearchService
+}
+
+func NewSavedSearchHandler() *SavedSearchHandler {
+	return &SavedSearchHandler{
+		savedSearchService: services.NewSavedSearchService(),
+	}
+}
+
+// CreateSavedSearch handles POST /me/saved-searches
+func (sh *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	var req models.CreateSavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedSearch, err := sh.savedSearchService.CreateSavedSearch(actorFrom(c), &req)
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrTooManySavedSearches) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrAlertsUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Search saved successfully",
+		"data":    savedSearch,
+	})
+}
+
+// ListSavedSearches handles GET /me/saved-searches
+func (sh *SavedSearchHandler) ListSavedSearches(c *gin.Context) {
+	savedSearches, err := sh.savedSearchService.ListSavedSearches(actorFrom(c))
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": savedSearches})
+}
+
+// DeleteSavedSearch handles DELETE /me/saved-searches/:id
+func (sh *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID"})
+		return
+	}
+
+	if err := sh.savedSearchService.DeleteSavedSearch(actorFrom(c), uint(id)); err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+			return
+		}
+		if errors.Is(err, services.ErrAlertsUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// ListNotifications handles GET /me/notifications?unread=true
+func (sh *SavedSearchHandler) ListNotifications(c *gin.Context) {
+	unreadOnly := false
+	if value := c.Query("unread"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'unread' must be a boolean"})
+			return
+		}
+		unreadOnly = parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	notifications, err := sh.savedSearchService.ListNotifications(actorFrom(c), unreadOnly, limit, offset)
+	if err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   notifications,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// MarkNotificationRead handles POST /me/notifications/:id/read
+func (sh *SavedSearchHandler) MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	if err := sh.savedSearchService.MarkNotificationRead(actorFrom(c), uint(id)); err != nil {
+		if respondForbidden(c, err) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"blog-api/internal/models"
+	"blog-api/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAnalyticsWindow is the report window when no from is given
+const defaultAnalyticsWindow = 7 * 24 * time.Hour
+
+type SearchAnalyticsHandler struct {
+	analyticsService *services.SearchAnalyticsService
+}
+
+func NewSearchAnalyticsHandler() *SearchAnalyticsHandler {
+	return &SearchAnalyticsHandler{
+		analyticsService: services.NewSearchAnalyticsService(),
+	}
+}
+
+// TopQueries handles GET /analytics/search/top-queries?from=&to=&limit=&min_searches=
+func (sh *SearchAnalyticsHandler) TopQueries(c *gin.Context) {
+	sh.report(c, sh.analyticsService.TopQueries)
+}
+
+// ZeroResultQueries handles GET /analytics/search/zero-results?from=&to=&limit=&min_searches=
+func (sh *SearchAnalyticsHandler) ZeroResultQueries(c *gin.Context) {
+	sh.report(c, sh.analyticsService.ZeroResultQueries)
+}
+
+// ClickThroughRates handles GET /analytics/search/click-through?from=&to=&limit=&min_searches=
+func (sh *SearchAnalyticsHandler) ClickThroughRates(c *gin.Context) {
+	sh.report(c, sh.analyticsService.ClickThroughRates)
+}
+
+// report parses the time window and limits shared by the reports. The window
+// defaults to the last seven days.
+func (sh *SearchAnalyticsHandler) report(c *gin.Context, run func(*models.SearchAnalyticsFilter) ([]models.SearchQueryStats, error)) {
+	filter := models.SearchAnalyticsFilter{
+		To: time.Now(),
+	}
+
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'to' must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.To = to
+	}
+	filter.From = filter.To.Add(-defaultAnalyticsWindow)
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'from' must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.From = from
+	}
+	if !filter.From.Before(filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter 'from' must be before 'to'"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	filter.Limit = limit
+
+	minSearches, err := strconv.Atoi(c.DefaultQuery("min_searches", "1"))
+	if err != nil || minSearches <= 0 {
+		minSearches = 1
+	}
+	filter.MinSearches = minSearches
+
+	stats, err := run(&filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": stats,
+		"from": filter.From,
+		"to":   filter.To,
+	})
+}
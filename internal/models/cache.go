@@ -0,0 +1,16 @@
+package models
+
+// CacheLayerStats counts lookups served, or not, by one cache layer
+type CacheLayerStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// CacheStats reports post cache lookups per layer since this instance
+// started. The local layer is per instance, so replicas report separately.
+type CacheStats struct {
+	Local         CacheLayerStats `json:"local"`
+	Redis         CacheLayerStats `json:"redis"`
+	LocalEntries  int             `json:"local_entries"`
+	LocalCapacity int             `json:"local_capacity"`
+}
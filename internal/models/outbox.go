@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Outbox event types. Each type is delivered to exactly one downstream system
+// so that a failure in one does not replay the side effects of another.
+const (
+	OutboxEventSearchIndex     = "search.index"
+	OutboxEventSearchDelete    = "search.delete"
+	OutboxEventCacheInvalidate = "cache.invalidate"
+)
+
+// Outbox event statuses
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusDead      = "dead"
+)
+
+// OutboxEvent is a side effect recorded in the same transaction as the post
+// change that caused it, and delivered later by the outbox dispatcher.
+type OutboxEvent struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	EventType     string     `json:"event_type" gorm:"size:50;not null"`
+	PostID        uint       `json:"post_id" gorm:"not null;index"`
+	Status        string     `json:"status" gorm:"size:20;not null;default:pending;index:idx_outbox_events_status_next_attempt,priority:1"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null;index:idx_outbox_events_status_next_attempt,priority:2"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+func (oe *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -1,67 +1,244 @@
-package models
-
-import (
-	"time"
-
-	"github.com/lib/pq"
-	"gorm.io/gorm"
-)
-
-type Post struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Title     string         `json:"title" gorm:"not null" binding:"required"`
-	Content   string         `json:"content" gorm:"type:text;not null" binding:"required"`
-	Tags      pq.StringArray `json:"tags" gorm:"type:text[]"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-}
-
-type ActivityLog struct {
-	ID       uint      `json:"id" gorm:"primaryKey"`
-	Action   string    `json:"action" gorm:"not null"`
-	PostID   uint      `json:"post_id" gorm:"not null"`
-	LoggedAt time.Time `json:"logged_at" gorm:"default:CURRENT_TIMESTAMP"`
-}
-
-type CreatePostRequest struct {
-	Title   string   `json:"title" binding:"required"`
-	Content string   `json:"content" binding:"required"`
-	Tags    []string `json:"tags"`
-}
-
-type UpdatePostRequest struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
-}
-
-type PostResponse struct {
-	ID           uint      `json:"id"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	Tags         []string  `json:"tags"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	RelatedPosts []Post    `json:"related_posts,omitempty"`
-}
-
-type SearchResponse struct {
-	Posts []Post `json:"posts"`
-	Total int64  `json:"total"`
-}
-
-// ElasticsearchPost represents post document in Elasticsearch
-type ElasticsearchPost struct {
-	ID      uint     `json:"id"`
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
-}
-
-func (p *Post) TableName() string {
-	return "posts"
-}
-
-func (al *ActivityLog) TableName() string {
-	return "activity_logs"
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Post statuses
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+type Post struct {
+	ID          uint           `json:"id" gorm:"primaryKey;index:idx_posts_created_at_id,priority:2,sort:desc"`
+	Title       string         `json:"title" gorm:"not null" binding:"required"`
+	Slug        string         `json:"slug" gorm:"size:255;uniqueIndex"`
+	Content     string         `json:"content" gorm:"type:text;not null" binding:"required"`
+	Tags        pq.StringArray `json:"tags" gorm:"type:text[]"`
+	AuthorID    *uint          `json:"author_id,omitempty" gorm:"index"`
+	Status      string         `json:"status" gorm:"size:20;not null;default:published;index"`
+	PublishAt   *time.Time     `json:"publish_at,omitempty" gorm:"index"`
+	PublishedAt *time.Time     `json:"published_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"index:idx_posts_created_at_id,priority:1,sort:desc"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// PostSlugRedirect keeps a post's previous slug resolving after its title changes
+type PostSlugRedirect struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Slug      string    `json:"slug" gorm:"size:255;not null;uniqueIndex"`
+	PostID    uint      `json:"post_id" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Activity log actions
+const (
+	ActivityNewPost     = "new_post"
+	ActivityUpdatePost  = "update_post"
+	ActivityDeletePost  = "delete_post"
+	ActivityPublishPost = "publish_post"
+	ActivityRestorePost = "restore_post"
+)
+
+// ActivityLog is the audit trail of post mutations. It has no foreign key to
+// posts so the history of a deleted post is kept.
+type ActivityLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	PostID    uint      `json:"post_id" gorm:"not null;index"`
+	UserID    *uint     `json:"user_id,omitempty" gorm:"index"`
+	RequestID string    `json:"request_id,omitempty" gorm:"size:64"`
+	Before    JSON      `json:"before,omitempty" gorm:"type:jsonb"`
+	After     JSON      `json:"after,omitempty" gorm:"type:jsonb"`
+	LoggedAt  time.Time `json:"logged_at" gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+type ActivityFilter struct {
+	PostID *uint
+	Action string
+	UserID *uint
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+type CreatePostRequest struct {
+	Title     string     `json:"title" binding:"required"`
+	Content   string     `json:"content" binding:"required"`
+	Tags      []string   `json:"tags"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type UpdatePostRequest struct {
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// Tag match modes of a post listing
+const (
+	TagModeAny = "any"
+	TagModeAll = "all"
+)
+
+// Sort orders of a post listing
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// PostListQuery holds the filters, sort and paging options of GET /posts
+type PostListQuery struct {
+	Tags        []string
+	TagMode     string
+	AuthorID    *uint
+	Status      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Sort        string
+	Order       string
+	Limit       int
+	Offset      int
+	Cursor      string
+}
+
+type PostListResult struct {
+	Posts          []Post
+	Total          int64
+	TotalEstimated bool
+	NextCursor     string
+	PrevCursor     string
+}
+
+type PostResponse struct {
+	ID           uint       `json:"id"`
+	Title        string     `json:"title"`
+	Slug         string     `json:"slug"`
+	Content      string     `json:"content"`
+	Tags         []string   `json:"tags"`
+	AuthorID     *uint      `json:"author_id,omitempty"`
+	Status       string     `json:"status"`
+	PublishAt    *time.Time `json:"publish_at,omitempty"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	RelatedPosts []Post     `json:"related_posts,omitempty"`
+}
+
+// SearchQuery holds a full-text query and its paging. Page and Size select a
+// page directly; SearchAfter is the opaque token of a previous response and
+// continues after it without the depth limit of page numbers.
+type SearchQuery struct {
+	Query       string
+	Tags        []string
+	From        *time.Time
+	To          *time.Time
+	Page        int
+	Size        int
+	SearchAfter string
+	// Autocorrect re-runs a query without hits with its spelling suggestion
+	Autocorrect bool
+}
+
+// SearchHit is one search result with its relevance score and the highlighted
+// fragments of the fields that matched
+type SearchHit struct {
+	Post       Post                `json:"post"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// FacetBucket is one value of a search facet and the number of matching posts
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// SearchFacets counts the matches of a query per tag and per creation month.
+// Each facet honours the other facet's filter but not its own, so the UI can
+// offer alternatives to the current selection.
+type SearchFacets struct {
+	Tags   []FacetBucket `json:"tags"`
+	Months []FacetBucket `json:"months"`
+}
+
+// Search engines a SearchResponse can come from
+const (
+	SearchEngineElasticsearch = "elasticsearch"
+	SearchEnginePostgres      = "postgres"
+)
+
+type SearchResponse struct {
+	// SearchID identifies the search in click reports; only first pages have one
+	SearchID        uint          `json:"search_id,omitempty"`
+	Engine          string        `json:"engine"`
+	Posts           []Post        `json:"posts"`
+	Hits            []SearchHit   `json:"hits"`
+	Facets          *SearchFacets `json:"facets,omitempty"`
+	Total           int64         `json:"total"`
+	Page            int           `json:"page"`
+	Size            int           `json:"size"`
+	NextSearchAfter string        `json:"next_search_after,omitempty"`
+	// Suggestion is a spelling correction offered when nothing matched.
+	// CorrectedFrom is set to the original query when the results are for
+	// the suggestion instead.
+	Suggestion    string `json:"suggestion,omitempty"`
+	CorrectedFrom string `json:"corrected_from,omitempty"`
+}
+
+// CompletionInput is the value of a completion suggester field
+type CompletionInput struct {
+	Input    []string            `json:"input"`
+	Contexts map[string][]string `json:"contexts,omitempty"`
+}
+
+// PostSuggestion is a lightweight title completion for the search box
+type PostSuggestion struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+type SuggestResponse struct {
+	Posts []PostSuggestion `json:"posts"`
+	Tags  []FacetBucket    `json:"tags"`
+}
+
+// ElasticsearchPost represents post document in Elasticsearch
+type ElasticsearchPost struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Content     string     `json:"content"`
+	Tags        []string   `json:"tags"`
+	AuthorID    *uint      `json:"author_id,omitempty"`
+	Status      string     `json:"status"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	TitleSuggest *CompletionInput `json:"title_suggest,omitempty"`
+}
+
+func (p *Post) TableName() string {
+	return "posts"
+}
+
+func (psr *PostSlugRedirect) TableName() string {
+	return "post_slug_redirects"
+}
+
+func (al *ActivityLog) TableName() string {
+	return "activity_logs"
 }
\ No newline at end of file
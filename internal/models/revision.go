@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is an immutable snapshot of a post taken on every save
+type PostRevision struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	PostID       uint           `json:"post_id" gorm:"not null;uniqueIndex:idx_post_revisions_post_version,priority:1"`
+	Version      int            `json:"version" gorm:"not null;uniqueIndex:idx_post_revisions_post_version,priority:2"`
+	Title        string         `json:"title" gorm:"not null"`
+	Content      string         `json:"content" gorm:"type:text;not null"`
+	Tags         pq.StringArray `json:"tags" gorm:"type:text[]"`
+	RestoredFrom *int           `json:"restored_from,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// DiffOp is one run of equal, inserted or deleted text in a diff
+type DiffOp struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Diff operation types
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type RevisionDiffResponse struct {
+	PostID      uint     `json:"post_id"`
+	From        int      `json:"from"`
+	To          int      `json:"to"`
+	Mode        string   `json:"mode"`
+	Title       []DiffOp `json:"title"`
+	Content     []DiffOp `json:"content"`
+	TagsAdded   []string `json:"tags_added"`
+	TagsRemoved []string `json:"tags_removed"`
+}
+
+func (pr *PostRevision) TableName() string {
+	return "post_revisions"
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SavedSearch is a query a user wants to be alerted about. Its query is also
+// registered in the Elasticsearch percolator so new posts can be matched.
+type SavedSearch struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	Name      string         `json:"name" gorm:"size:100;not null"`
+	Query     string         `json:"query" gorm:"type:text;not null"`
+	Tags      pq.StringArray `json:"tags" gorm:"type:text[]"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// SearchNotification tells a user that a post matched one of their saved
+// searches. A post is notified at most once per saved search. The title and
+// slug are those of the post when it matched.
+type SearchNotification struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index:idx_search_notifications_user_id_created_at,priority:1"`
+	SavedSearchID uint       `json:"saved_search_id" gorm:"not null;uniqueIndex:idx_search_notifications_saved_search_post,priority:1"`
+	PostID        uint       `json:"post_id" gorm:"not null;uniqueIndex:idx_search_notifications_saved_search_post,priority:2"`
+	PostTitle     string     `json:"post_title"`
+	PostSlug      string     `json:"post_slug" gorm:"size:255"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"index:idx_search_notifications_user_id_created_at,priority:2,sort:desc"`
+}
+
+type CreateSavedSearchRequest struct {
+	Name  string   `json:"name" binding:"required,max=100"`
+	Query string   `json:"query" binding:"required"`
+	Tags  []string `json:"tags"`
+}
+
+func (ss *SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+func (sn *SearchNotification) TableName() string {
+	return "search_notifications"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// SearchLog records one search for analytics. Only the first page of a search
+// is logged; ClickedPostID is the first result the reader opened from it.
+type SearchLog struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Query           string     `json:"query" gorm:"type:text;not null"`
+	NormalizedQuery string     `json:"normalized_query" gorm:"size:255;not null;index"`
+	ResultCount     int64      `json:"result_count" gorm:"not null"`
+	Engine          string     `json:"engine" gorm:"size:20"`
+	LatencyMS       int64      `json:"latency_ms" gorm:"not null"`
+	UserID          *uint      `json:"user_id,omitempty" gorm:"index"`
+	RequestID       string     `json:"request_id,omitempty" gorm:"size:64"`
+	ClickedPostID   *uint      `json:"clicked_post_id,omitempty"`
+	ClickedAt       *time.Time `json:"clicked_at,omitempty"`
+	SearchedAt      time.Time  `json:"searched_at" gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+func (sl *SearchLog) TableName() string {
+	return "search_logs"
+}
+
+// SearchAnalyticsFilter selects the searches a report covers
+type SearchAnalyticsFilter struct {
+	From  time.Time
+	To    time.Time
+	Limit int
+	// MinSearches leaves out queries searched fewer times in the window
+	MinSearches int
+}
+
+// SearchQueryStats aggregates the searches for one normalized query
+type SearchQueryStats struct {
+	Query            string    `json:"query"`
+	Searches         int64     `json:"searches"`
+	ZeroResults      int64     `json:"zero_results"`
+	Clicks           int64     `json:"clicks"`
+	ClickThroughRate float64   `json:"click_through_rate"`
+	AvgResults       float64   `json:"avg_results"`
+	AvgLatencyMS     float64   `json:"avg_latency_ms"`
+	LastSearchedAt   time.Time `json:"last_searched_at"`
+}
+
+// SearchClickRequest reports which result of a search the reader opened
+type SearchClickRequest struct {
+	SearchID uint `json:"search_id" binding:"required"`
+	PostID   uint `json:"post_id" binding:"required"`
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// maxActivityPageSize caps GET /activity responses
+const maxActivityPageSize = 200
+
+type ActivityService struct {
+	db *gorm.DB
+}
+
+func NewActivityService() *ActivityService {
+	return &ActivityService{
+		db: database.GetDB(),
+	}
+}
+
+// logActivity writes an audit entry inside the caller's transaction. before and
+// after are snapshots of the post around the change; either may be nil.
+func logActivity(tx *gorm.DB, actor Actor, action string, postID uint, before, after *models.Post) error {
+	activityLog := &models.ActivityLog{
+		Action:    action,
+		PostID:    postID,
+		UserID:    actor.UserID(),
+		RequestID: actor.RequestID,
+	}
+
+	if before != nil {
+		snapshot, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		activityLog.Before = snapshot
+	}
+	if after != nil {
+		snapshot, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		activityLog.After = snapshot
+	}
+
+	return tx.Create(activityLog).Error
+}
+
+// ListActivity returns audit entries matching the filter, newest first. The
+// filter's Limit is set to the page size actually used.
+func (as *ActivityService) ListActivity(actor Actor, filter *models.ActivityFilter) ([]models.ActivityLog, error) {
+	if err := canViewActivity(actor); err != nil {
+		return nil, err
+	}
+
+	query := as.db.Model(&models.ActivityLog{})
+	if filter.PostID != nil {
+		query = query.Where("post_id = ?", *filter.PostID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("logged_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("logged_at < ?", *filter.To)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > maxActivityPageSize {
+		limit = maxActivityPageSize
+	}
+	filter.Limit = limit
+
+	var logs []models.ActivityLog
+	if err := query.Order("logged_at DESC, id DESC").Limit(limit).Offset(filter.Offset).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+)
+
+// Token types carried in the "token_type" claim so a refresh token can never
+// be used as an access token and vice versa
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+type tokenClaims struct {
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+type AuthService struct {
+	db  *gorm.DB
+	cfg *config.AuthConfig
+}
+
+func NewAuthService(cfg *config.AuthConfig) *AuthService {
+	return &AuthService{
+		db:  database.GetDB(),
+		cfg: cfg,
+	}
+}
+
+// Register creates a user account and signs the user in
+func (as *AuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	username := strings.TrimSpace(req.Username)
+
+	var count int64
+	if err := as.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrEmailTaken
+	}
+	if err := as.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrUsernameTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every account starts as a reader; the first admin is granted with
+	// "./main grant-admin" so nobody can claim a fresh deploy by signing up
+	user := &models.User{
+		Email:        email,
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         models.RoleReader,
+	}
+
+	// The checks above race with concurrent sign-ups, so the unique indexes
+	// have the last word
+	if err := as.db.Create(user).Error; err != nil {
+		switch uniqueViolation(err) {
+		case "idx_users_email":
+			return nil, ErrEmailTaken
+		case "idx_users_username":
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	return as.issueTokens(user)
+}
+
+// uniqueViolation returns the constraint a unique violation was raised by, or
+// "" for any other error
+func uniqueViolation(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// Login verifies credentials and returns a fresh token pair
+func (as *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+	var user models.User
+	err := as.db.Where("email = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return as.issueTokens(&user)
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (as *AuthService) Refresh(refreshToken string) (*models.AuthResponse, error) {
+	user, err := as.userFromToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return as.issueTokens(user)
+}
+
+// Authenticate resolves the user behind an access token
+func (as *AuthService) Authenticate(accessToken string) (*models.User, error) {
+	return as.userFromToken(accessToken, tokenTypeAccess)
+}
+
+func (as *AuthService) userFromToken(tokenString, tokenType string) (*models.User, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(as.cfg.JWTSecret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(as.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Load the user so deleted accounts lose access immediately
+	var user models.User
+	err = as.db.First(&user, uint(userID)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (as *AuthService) issueTokens(user *models.User) (*models.AuthResponse, error) {
+	accessToken, err := as.signToken(user, tokenTypeAccess, as.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := as.signToken(user, tokenTypeRefresh, as.cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(as.cfg.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+func (as *AuthService) signToken(user *models.User, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := tokenClaims{
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Issuer:    as.cfg.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(as.cfg.JWTSecret))
+}
@@ -1,86 +1,452 @@
-package services
-
-import (
-	"blog-api/internal/database"
-	"blog-api/internal/models"
-	"context"
-	"encoding/json"
-	"fmt"
-	"time"
-
-	"github.com/go-redis/redis/v8"
-)
-
-type CacheService struct {
-	redis *redis.Client
-}
-
-func NewCacheService() *CacheService {
-	return &CacheService{
-		redis: database.GetRedis(),
-	}
-}
-
-const (
-	PostCacheKeyPrefix = "post:"
-	PostCacheTTL       = 5 * time.Minute
-)
-
-// GetPost retrieves a post from cache
-func (cs *CacheService) GetPost(id uint) (*models.Post, error) {
-	ctx := context.Background()
-	key := fmt.Sprintf("%s%d", PostCacheKeyPrefix, id)
-
-	val, err := cs.redis.Get(ctx, key).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return nil, nil // Cache miss
-		}
-		return nil, err
-	}
-
-	var post models.Post
-	err = json.Unmarshal([]byte(val), &post)
-	if err != nil {
-		return nil, err
-	}
-
-	return &post, nil
-}
-
-// SetPost stores a post in cache with TTL
-func (cs *CacheService) SetPost(post *models.Post) error {
-	ctx := context.Background()
-	key := fmt.Sprintf("%s%d", PostCacheKeyPrefix, post.ID)
-
-	postJSON, err := json.Marshal(post)
-	if err != nil {
-		return err
-	}
-
-	return cs.redis.Set(ctx, key, postJSON, PostCacheTTL).Err()
-}
-
-// InvalidatePost removes a post from cache
-func (cs *CacheService) InvalidatePost(id uint) error {
-	ctx := context.Background()
-	key := fmt.Sprintf("%s%d", PostCacheKeyPrefix, id)
-
-	return cs.redis.Del(ctx, key).Err()
-}
-
-// InvalidatePostsByPattern removes posts from cache by pattern
-func (cs *CacheService) InvalidatePostsByPattern(pattern string) error {
-	ctx := context.Background()
-
-	keys, err := cs.redis.Keys(ctx, pattern).Result()
-	if err != nil {
-		return err
-	}
-
-	if len(keys) > 0 {
-		return cs.redis.Del(ctx, keys...).Err()
-	}
-
-	return nil
-}
\ No newline at end of file
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type CacheService struct {
+	redis *redis.Client
+}
+
+func NewCacheService() *CacheService {
+	return &CacheService{
+		redis: database.GetRedis(),
+	}
+}
+
+const (
+	PostCacheKeyPrefix = "post:"
+	PostCacheTTL       = 5 * time.Minute
+
+	// Related posts of a post are kept in one hash per post and listing
+	// generation, with a field per strategy and visibility. Any post change
+	// bumps the generation, so lists that include a changed, unpublished or
+	// deleted post are orphaned along with the lists of the post itself.
+	RelatedCacheKeyPrefix = "related:"
+	RelatedCacheTTL       = 10 * time.Minute
+
+	// Listings and tag queries are cached under keys that embed a generation
+	// number. Any post change bumps the generation, which orphans every cached
+	// listing at once without looking for keys; orphans expire with their TTL.
+	ListGenerationKey   = "posts:generation"
+	ListCacheKeyPrefix  = "posts:list:"
+	TagPostsCachePrefix = "posts:tag:"
+	ListCacheTTL        = 2 * time.Minute
+
+	// Pattern invalidation scans this many keys per round trip and gives up
+	// after the timeout rather than holding the caller indefinitely
+	PatternInvalidationBatchSize = 500
+	PatternInvalidationTimeout   = 5 * time.Second
+
+	// IDs with no post are remembered briefly, so scans of missing IDs don't
+	// reach Postgres every time. Creating a post clears its tombstone.
+	MissingPostKeyPrefix = "post-missing:"
+	MissingPostTTL       = 30 * time.Second
+
+	// Only the holder of a post's load lock reads it from Postgres after a
+	// cache miss; other instances poll the cache until the holder fills it,
+	// and fall back to Postgres themselves if the lock outlives the wait.
+	PostLockKeyPrefix    = "lock:post:"
+	PostLockTTL          = 5 * time.Second
+	PostLockWait         = time.Second
+	PostLockPollInterval = 50 * time.Millisecond
+
+	// TTLs are stretched by up to this fraction so entries written together
+	// don't all expire together
+	cacheTTLJitter = 0.1
+)
+
+// releaseLockScript deletes a lock only if it still holds our token, so a
+// holder whose lock expired can't release the next holder's lock
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// jitteredTTL returns ttl lengthened by a random share of up to cacheTTLJitter
+func jitteredTTL(ttl time.Duration) time.Duration {
+	return ttl + time.Duration(rand.Int63n(int64(float64(ttl)*cacheTTLJitter)+1))
+}
+
+// GetPost retrieves a post from the local cache, or from Redis on a local
+// miss, counting hits and misses per layer
+func (cs *CacheService) GetPost(id uint) (*models.Post, error) {
+	if post := localPosts.get(id); post != nil {
+		return post, nil
+	}
+
+	post, err := cs.redisPost(id)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil {
+		redisPostStats.misses.Add(1)
+		return nil, nil
+	}
+	redisPostStats.hits.Add(1)
+	localPosts.set(post)
+	return post, nil
+}
+
+// redisPost reads a post from Redis alone, so reconciliation sees what
+// Redis holds rather than this instance's local copy
+func (cs *CacheService) redisPost(id uint) (*models.Post, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", PostCacheKeyPrefix, id)
+
+	val, err := cs.redis.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Cache miss
+		}
+		return nil, err
+	}
+
+	var post models.Post
+	err = json.Unmarshal([]byte(val), &post)
+	if err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// SetPost stores a post in cache with TTL
+func (cs *CacheService) SetPost(post *models.Post) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", PostCacheKeyPrefix, post.ID)
+
+	postJSON, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.redis.Set(ctx, key, postJSON, jitteredTTL(PostCacheTTL)).Err(); err != nil {
+		return err
+	}
+	localPosts.set(post)
+	return nil
+}
+
+// AcquirePostLock tries to take the load lock of a post. It returns the token
+// to release it with, or "" when another instance holds it.
+func (cs *CacheService) AcquirePostLock(id uint) (string, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", PostLockKeyPrefix, id)
+	token := strconv.FormatInt(rand.Int63(), 36)
+
+	acquired, err := cs.redis.SetNX(ctx, key, token, PostLockTTL).Result()
+	if err != nil || !acquired {
+		return "", err
+	}
+	return token, nil
+}
+
+// ReleasePostLock releases a load lock taken with AcquirePostLock
+func (cs *CacheService) ReleasePostLock(id uint, token string) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", PostLockKeyPrefix, id)
+
+	return releaseLockScript.Run(ctx, cs.redis, []string{key}, token).Err()
+}
+
+// WaitForPost polls Redis for a post another instance is loading. It returns
+// ErrPostNotFound if the loader found no post, and nil if neither the post nor
+// its tombstone appeared within PostLockWait. Nothing here is counted in the
+// cache stats: the caller's GetPost already recorded the lookup as a miss.
+func (cs *CacheService) WaitForPost(id uint) (*models.Post, error) {
+	deadline := time.Now().Add(PostLockWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(PostLockPollInterval)
+
+		post, err := cs.redisPost(id)
+		if err != nil {
+			return nil, err
+		}
+		if post != nil {
+			localPosts.set(post)
+			return post, nil
+		}
+		missing, err := cs.IsPostMissing(id)
+		if err != nil {
+			return nil, err
+		}
+		if missing {
+			return nil, ErrPostNotFound
+		}
+	}
+	return nil, nil
+}
+
+// IsPostMissing reports whether a tombstone says no post has this ID
+func (cs *CacheService) IsPostMissing(id uint) (bool, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", MissingPostKeyPrefix, id)
+
+	n, err := cs.redis.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+// SetPostMissing stores a tombstone for an ID with no post
+func (cs *CacheService) SetPostMissing(id uint) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", MissingPostKeyPrefix, id)
+
+	return cs.redis.Set(ctx, key, 1, jitteredTTL(MissingPostTTL)).Err()
+}
+
+// ClearPostMissing removes the tombstone of an ID once a post has it
+func (cs *CacheService) ClearPostMissing(id uint) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", MissingPostKeyPrefix, id)
+
+	return cs.redis.Del(ctx, key).Err()
+}
+
+// InvalidatePost removes a post and any tombstone for its ID from cache, and
+// bumps the listing generation again in case the bump made when the change
+// committed was lost. The bump also orphans cached related posts.
+func (cs *CacheService) InvalidatePost(id uint) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", PostCacheKeyPrefix, id)
+	missingKey := fmt.Sprintf("%s%d", MissingPostKeyPrefix, id)
+
+	pipe := cs.redis.TxPipeline()
+	pipe.Del(ctx, key, missingKey)
+	pipe.Incr(ctx, ListGenerationKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// Replicas may have refilled their local cache from Redis since the post
+	// changed, so they are told again now that Redis is clean
+	return cs.PublishPostInvalidation(id)
+}
+
+// PublishPostInvalidation drops a post from the local cache of this and
+// every other instance
+func (cs *CacheService) PublishPostInvalidation(id uint) error {
+	ctx := context.Background()
+
+	localPosts.remove(id)
+	return cs.redis.Publish(ctx, PostInvalidationChannel, strconv.FormatUint(uint64(id), 10)).Err()
+}
+
+// Stats reports post cache hits and misses per layer for this instance
+func (cs *CacheService) Stats() *models.CacheStats {
+	stats := &models.CacheStats{
+		Redis:        redisPostStats.snapshot(),
+		LocalEntries: localPosts.len(),
+	}
+	if localPosts != nil {
+		stats.Local = localPosts.stats.snapshot()
+		stats.LocalCapacity = localPosts.capacity
+	}
+	return stats
+}
+
+// BumpListGeneration moves listings to a new generation, orphaning every
+// cached listing at once
+func (cs *CacheService) BumpListGeneration() error {
+	ctx := context.Background()
+
+	return cs.redis.Incr(ctx, ListGenerationKey).Err()
+}
+
+// ListGeneration returns the current generation of listing cache keys
+func (cs *CacheService) ListGeneration() (int64, error) {
+	ctx := context.Background()
+
+	generation, err := cs.redis.Get(ctx, ListGenerationKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return generation, err
+}
+
+// PostListCacheKey builds the key of a listing page for a visibility scope.
+// The query is normalized first, so equivalent requests share a key.
+func PostListCacheKey(generation int64, scope string, query *models.PostListQuery) (string, error) {
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(queryJSON)
+	return fmt.Sprintf("%s%d:%s:%x", ListCacheKeyPrefix, generation, scope, sum), nil
+}
+
+// RelatedCacheKey builds the key of the related posts hash of a post
+func RelatedCacheKey(generation int64, id uint) string {
+	return fmt.Sprintf("%s%d:%d", RelatedCacheKeyPrefix, generation, id)
+}
+
+// TagPostsCacheKey builds the key of the posts with a tag for a visibility scope
+func TagPostsCacheKey(generation int64, scope, tag string) string {
+	return fmt.Sprintf("%s%d:%s:%s", TagPostsCachePrefix, generation, scope, tag)
+}
+
+// GetPostList retrieves a cached listing page; a miss returns nil
+func (cs *CacheService) GetPostList(key string) (*models.PostListResult, error) {
+	var result models.PostListResult
+	found, err := cs.getJSON(key, &result)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetPostList stores a listing page
+func (cs *CacheService) SetPostList(key string, result *models.PostListResult) error {
+	return cs.setJSON(key, result, ListCacheTTL)
+}
+
+// GetTagPosts retrieves the cached posts with a tag; a miss returns nil and a
+// cached empty list is not nil
+func (cs *CacheService) GetTagPosts(key string) ([]models.Post, error) {
+	posts := []models.Post{}
+	found, err := cs.getJSON(key, &posts)
+	if err != nil || !found {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// SetTagPosts stores the posts with a tag
+func (cs *CacheService) SetTagPosts(key string, posts []models.Post) error {
+	if posts == nil {
+		posts = []models.Post{}
+	}
+	return cs.setJSON(key, posts, ListCacheTTL)
+}
+
+// getJSON decodes the value at key into dest and reports whether it existed
+func (cs *CacheService) getJSON(key string, dest interface{}) (bool, error) {
+	ctx := context.Background()
+
+	val, err := cs.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := json.Unmarshal(val, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (cs *CacheService) setJSON(key string, value interface{}, ttl time.Duration) error {
+	ctx := context.Background()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return cs.redis.Set(ctx, key, data, jitteredTTL(ttl)).Err()
+}
+
+// GetRelatedPosts retrieves the related posts of a post stored under field
+// for a listing generation. A cache miss returns a nil slice; a cached empty
+// list is not nil.
+func (cs *CacheService) GetRelatedPosts(generation int64, id uint, field string) ([]models.Post, error) {
+	ctx := context.Background()
+	key := RelatedCacheKey(generation, id)
+
+	val, err := cs.redis.HGet(ctx, key, field).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // Cache miss
+		}
+		return nil, err
+	}
+
+	posts := []models.Post{}
+	if err := json.Unmarshal([]byte(val), &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// SetRelatedPosts stores the related posts of a post under field for a
+// listing generation
+func (cs *CacheService) SetRelatedPosts(generation int64, id uint, field string, posts []models.Post) error {
+	ctx := context.Background()
+	key := RelatedCacheKey(generation, id)
+
+	if posts == nil {
+		posts = []models.Post{}
+	}
+	postsJSON, err := json.Marshal(posts)
+	if err != nil {
+		return err
+	}
+
+	pipe := cs.redis.TxPipeline()
+	pipe.HSet(ctx, key, field, postsJSON)
+	pipe.Expire(ctx, key, jitteredTTL(RelatedCacheTTL))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// CachedPostIDs lists the IDs of the posts currently cached. It walks the
+// keyspace with SCAN so Redis is never blocked.
+func (cs *CacheService) CachedPostIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	iter := cs.redis.Scan(ctx, 0, PostCacheKeyPrefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		id, err := strconv.ParseUint(strings.TrimPrefix(iter.Val(), PostCacheKeyPrefix), 10, 32)
+		if err != nil {
+			// Not a post entry
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, iter.Err()
+}
+
+// InvalidatePostsByPattern removes the keys matching pattern and returns how
+// many were removed. The keyspace is walked with SCAN and each batch is
+// unlinked as it is found, so Redis is never blocked; the walk stops after
+// PatternInvalidationTimeout (or earlier if ctx ends), returning the keys
+// removed so far with the context error.
+func (cs *CacheService) InvalidatePostsByPattern(ctx context.Context, pattern string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, PatternInvalidationTimeout)
+	defer cancel()
+
+	var removed int64
+	var cursor uint64
+	for {
+		keys, next, err := cs.redis.Scan(ctx, cursor, pattern, PatternInvalidationBatchSize).Result()
+		if err != nil {
+			return removed, err
+		}
+		if len(keys) > 0 {
+			n, err := cs.redis.Unlink(ctx, keys...).Result()
+			removed += n
+			if err != nil {
+				return removed, err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return removed, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+	}
+}
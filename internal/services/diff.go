@@ -0,0 +1,152 @@
+package services
+
+import (
+	"blog-api/internal/models"
+	"regexp"
+	"strings"
+)
+
+// Diff modes
+const (
+	DiffModeLine = "line"
+	DiffModeWord = "word"
+)
+
+// maxDiffCells bounds the LCS table. Larger inputs fall back to a single
+// delete/insert pair for the changed middle section.
+const maxDiffCells = 1_000_000
+
+var wordTokenPattern = regexp.MustCompile(`\s+|[^\s]+`)
+
+// DiffText compares two texts line by line or word by word
+func DiffText(from, to, mode string) []models.DiffOp {
+	var a, b []string
+	if mode == DiffModeWord {
+		a = wordTokenPattern.FindAllString(from, -1)
+		b = wordTokenPattern.FindAllString(to, -1)
+	} else {
+		a = splitLines(from)
+		b = splitLines(to)
+	}
+	return diffTokens(a, b)
+}
+
+// splitLines splits text into lines, keeping the trailing newline on each
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func diffTokens(a, b []string) []models.DiffOp {
+	var ops []models.DiffOp
+
+	// Strip common prefix and suffix so the LCS only covers the changed part
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops = appendOp(ops, models.DiffEqual, a[:prefix])
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		ops = appendOp(ops, models.DiffDelete, midA)
+		ops = appendOp(ops, models.DiffInsert, midB)
+	} else {
+		ops = append(ops, lcsDiff(midA, midB)...)
+	}
+
+	ops = appendOp(ops, models.DiffEqual, a[len(a)-suffix:])
+	return mergeOps(ops)
+}
+
+func lcsDiff(a, b []string) []models.DiffOp {
+	// lengths[i][j] is the LCS length of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var ops []models.DiffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = appendOp(ops, models.DiffEqual, a[i:i+1])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			ops = appendOp(ops, models.DiffDelete, a[i:i+1])
+			i++
+		default:
+			ops = appendOp(ops, models.DiffInsert, b[j:j+1])
+			j++
+		}
+	}
+	ops = appendOp(ops, models.DiffDelete, a[i:])
+	ops = appendOp(ops, models.DiffInsert, b[j:])
+	return ops
+}
+
+func appendOp(ops []models.DiffOp, opType string, tokens []string) []models.DiffOp {
+	if len(tokens) == 0 {
+		return ops
+	}
+	return append(ops, models.DiffOp{Type: opType, Text: strings.Join(tokens, "")})
+}
+
+// mergeOps joins adjacent operations of the same type
+func mergeOps(ops []models.DiffOp) []models.DiffOp {
+	merged := make([]models.DiffOp, 0, len(ops))
+	for _, op := range ops {
+		if n := len(merged); n > 0 && merged[n-1].Type == op.Type {
+			merged[n-1].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}
+
+// diffTags returns the tags added and removed between two tag lists
+func diffTags(from, to []string) (added, removed []string) {
+	fromSet := make(map[string]bool, len(from))
+	for _, tag := range from {
+		fromSet[tag] = true
+	}
+	toSet := make(map[string]bool, len(to))
+	for _, tag := range to {
+		toSet[tag] = true
+		if !fromSet[tag] {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range from {
+		if !toSet[tag] {
+			removed = append(removed, tag)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"blog-api/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestDiffText(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		mode     string
+		want     []models.DiffOp
+	}{
+		{
+			name: "identical",
+			from: "a\nb\n", to: "a\nb\n", mode: DiffModeLine,
+			want: []models.DiffOp{{Type: models.DiffEqual, Text: "a\nb\n"}},
+		},
+		{
+			name: "both empty",
+			from: "", to: "", mode: DiffModeLine,
+			want: []models.DiffOp{},
+		},
+		{
+			name: "from empty",
+			from: "", to: "a\n", mode: DiffModeLine,
+			want: []models.DiffOp{{Type: models.DiffInsert, Text: "a\n"}},
+		},
+		{
+			name: "to empty",
+			from: "a\n", to: "", mode: DiffModeLine,
+			want: []models.DiffOp{{Type: models.DiffDelete, Text: "a\n"}},
+		},
+		{
+			name: "line changed in the middle",
+			from: "a\nb\nc\n", to: "a\nx\nc\n", mode: DiffModeLine,
+			want: []models.DiffOp{
+				{Type: models.DiffEqual, Text: "a\n"},
+				{Type: models.DiffDelete, Text: "b\n"},
+				{Type: models.DiffInsert, Text: "x\n"},
+				{Type: models.DiffEqual, Text: "c\n"},
+			},
+		},
+		{
+			name: "line inserted",
+			from: "a\nc\n", to: "a\nb\nc\n", mode: DiffModeLine,
+			want: []models.DiffOp{
+				{Type: models.DiffEqual, Text: "a\n"},
+				{Type: models.DiffInsert, Text: "b\n"},
+				{Type: models.DiffEqual, Text: "c\n"},
+			},
+		},
+		{
+			name: "last line without newline",
+			from: "a\nb", to: "a\nb\n", mode: DiffModeLine,
+			want: []models.DiffOp{
+				{Type: models.DiffEqual, Text: "a\n"},
+				{Type: models.DiffDelete, Text: "b"},
+				{Type: models.DiffInsert, Text: "b\n"},
+			},
+		},
+		{
+			name: "lcs keeps common lines between changes",
+			from: "a\nb\nc\nd\n", to: "b\nx\nd\ny\n", mode: DiffModeLine,
+			want: []models.DiffOp{
+				{Type: models.DiffDelete, Text: "a\n"},
+				{Type: models.DiffEqual, Text: "b\n"},
+				{Type: models.DiffDelete, Text: "c\n"},
+				{Type: models.DiffInsert, Text: "x\n"},
+				{Type: models.DiffEqual, Text: "d\n"},
+				{Type: models.DiffInsert, Text: "y\n"},
+			},
+		},
+		{
+			name: "word changed",
+			from: "the quick fox", to: "the slow fox", mode: DiffModeWord,
+			want: []models.DiffOp{
+				{Type: models.DiffEqual, Text: "the "},
+				{Type: models.DiffDelete, Text: "quick"},
+				{Type: models.DiffInsert, Text: "slow"},
+				{Type: models.DiffEqual, Text: " fox"},
+			},
+		},
+		{
+			name: "whitespace is a token in word mode",
+			from: "a b", to: "a  b", mode: DiffModeWord,
+			want: []models.DiffOp{
+				{Type: models.DiffEqual, Text: "a"},
+				{Type: models.DiffDelete, Text: " "},
+				{Type: models.DiffInsert, Text: "  "},
+				{Type: models.DiffEqual, Text: "b"},
+			},
+		},
+		{
+			name: "unknown mode diffs lines",
+			from: "a b\n", to: "a c\n", mode: "",
+			want: []models.DiffOp{
+				{Type: models.DiffDelete, Text: "a b\n"},
+				{Type: models.DiffInsert, Text: "a c\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffText(tt.from, tt.to, tt.mode)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffText(%q, %q, %q) = %#v, want %#v", tt.from, tt.to, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTags(t *testing.T) {
+	tests := []struct {
+		name                 string
+		from, to             []string
+		wantAdded, wantRemov []string
+	}{
+		{"unchanged", []string{"go", "api"}, []string{"api", "go"}, nil, nil},
+		{"added and removed", []string{"go", "api"}, []string{"go", "db"}, []string{"db"}, []string{"api"}},
+		{"from nothing", nil, []string{"go"}, []string{"go"}, nil},
+		{"to nothing", []string{"go"}, nil, nil, []string{"go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffTags(tt.from, tt.to)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(removed, tt.wantRemov) {
+				t.Errorf("diffTags(%v, %v) = %v, %v, want %v, %v", tt.from, tt.to, added, removed, tt.wantAdded, tt.wantRemov)
+			}
+		})
+	}
+}
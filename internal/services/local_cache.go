@@ -0,0 +1,203 @@
+package services
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"container/list"
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PostInvalidationChannel is the Redis pub/sub channel on which instances
+// announce changed posts, so every replica drops them from its local cache
+const PostInvalidationChannel = "cache:invalidate:post"
+
+// localPosts is the in-process cache in front of Redis, shared by every
+// CacheService of this instance. It stays nil, and is skipped, until
+// InitLocalCache is called.
+var localPosts *localCache
+
+// redisPostStats counts post lookups that reached Redis
+var redisPostStats layerStats
+
+type layerStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (s *layerStats) snapshot() models.CacheLayerStats {
+	return models.CacheLayerStats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
+
+// localCache is a size-bounded LRU of posts whose entries also expire after a
+// TTL, which bounds staleness when an invalidation message is missed
+type localCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // front is most recently used
+	entries  map[uint]*list.Element
+	stats    layerStats
+}
+
+type localEntry struct {
+	post    models.Post
+	expires time.Time
+}
+
+// InitLocalCache enables the in-process post cache. A size of 0 leaves it
+// disabled.
+func InitLocalCache(cfg *config.CacheConfig) {
+	if cfg.LocalSize <= 0 {
+		return
+	}
+	localPosts = &localCache{
+		capacity: cfg.LocalSize,
+		ttl:      cfg.LocalTTL,
+		order:    list.New(),
+		entries:  make(map[uint]*list.Element),
+	}
+}
+
+// get returns a copy of a cached post, or nil on a miss
+func (lc *localCache) get(id uint) *models.Post {
+	if lc == nil {
+		return nil
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	element, ok := lc.entries[id]
+	if !ok {
+		lc.stats.misses.Add(1)
+		return nil
+	}
+	entry := element.Value.(*localEntry)
+	if time.Now().After(entry.expires) {
+		lc.removeElement(id, element)
+		lc.stats.misses.Add(1)
+		return nil
+	}
+
+	lc.order.MoveToFront(element)
+	lc.stats.hits.Add(1)
+	post := entry.post
+	return &post
+}
+
+// set stores a copy of a post, evicting the least recently used entry when full
+func (lc *localCache) set(post *models.Post) {
+	if lc == nil {
+		return
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entry := &localEntry{post: *post, expires: time.Now().Add(lc.ttl)}
+	if element, ok := lc.entries[post.ID]; ok {
+		element.Value = entry
+		lc.order.MoveToFront(element)
+		return
+	}
+
+	lc.entries[post.ID] = lc.order.PushFront(entry)
+	if lc.order.Len() > lc.capacity {
+		oldest := lc.order.Back()
+		lc.removeElement(oldest.Value.(*localEntry).post.ID, oldest)
+	}
+}
+
+// remove drops a post if it is cached
+func (lc *localCache) remove(id uint) {
+	if lc == nil {
+		return
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if element, ok := lc.entries[id]; ok {
+		lc.removeElement(id, element)
+	}
+}
+
+func (lc *localCache) removeElement(id uint, element *list.Element) {
+	lc.order.Remove(element)
+	delete(lc.entries, id)
+}
+
+// clear drops every cached post
+func (lc *localCache) clear() {
+	if lc == nil {
+		return
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.order.Init()
+	lc.entries = make(map[uint]*list.Element)
+}
+
+func (lc *localCache) len() int {
+	if lc == nil {
+		return 0
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return lc.order.Len()
+}
+
+// CacheInvalidationListener drops posts from the local cache when any
+// instance announces a change on PostInvalidationChannel
+type CacheInvalidationListener struct{}
+
+func NewCacheInvalidationListener() *CacheInvalidationListener {
+	return &CacheInvalidationListener{}
+}
+
+// Run listens until ctx is cancelled. The client resubscribes after a lost
+// connection; messages sent meanwhile are missed, so the whole local cache
+// is dropped once the subscription is back.
+func (cl *CacheInvalidationListener) Run(ctx context.Context) {
+	if localPosts == nil {
+		return
+	}
+
+	pubsub := database.GetRedis().Subscribe(ctx, PostInvalidationChannel)
+	defer pubsub.Close()
+
+	log.Printf("Listening for post cache invalidations on %s", PostInvalidationChannel)
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error receiving cache invalidation: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// Sent on every (re)subscription; anything may have changed
+			// while we weren't subscribed
+			if msg.Kind == "subscribe" {
+				localPosts.clear()
+			}
+		case *redis.Message:
+			id, err := strconv.ParseUint(msg.Payload, 10, 32)
+			if err != nil {
+				log.Printf("Ignoring cache invalidation with invalid post id %q", msg.Payload)
+				continue
+			}
+			localPosts.remove(uint(id))
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrOutboxEventNotDead is returned when retrying an event that has not been dead-lettered
+var ErrOutboxEventNotDead = errors.New("only dead outbox events can be retried")
+
+type OutboxService struct {
+	db            *gorm.DB
+	cfg           *config.OutboxConfig
+	cacheService  *CacheService
+	searchService *SearchService
+}
+
+func NewOutboxService(cfg *config.OutboxConfig) *OutboxService {
+	return &OutboxService{
+		db:            database.GetDB(),
+		cfg:           cfg,
+		cacheService:  NewCacheService(),
+		searchService: NewSearchService(),
+	}
+}
+
+// EnqueuePostEvents records outbox events for a post inside the caller's transaction
+func EnqueuePostEvents(tx *gorm.DB, postID uint, eventTypes ...string) error {
+	now := time.Now()
+	for _, eventType := range eventTypes {
+		event := &models.OutboxEvent{
+			EventType:     eventType,
+			PostID:        postID,
+			Status:        models.OutboxStatusPending,
+			NextAttemptAt: now,
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run polls the outbox and delivers pending events until ctx is cancelled
+func (ob *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(ob.cfg.PollInterval)
+	defer ticker.Stop()
+
+	log.Printf("Outbox dispatcher started (poll interval %s)", ob.cfg.PollInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+			// Keep draining while full batches come back
+			for {
+				processed, err := ob.DispatchBatch()
+				if err != nil {
+					log.Printf("Error dispatching outbox events: %v", err)
+					break
+				}
+				if processed < ob.cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// DispatchBatch claims a batch of due events and delivers them. Claiming is a
+// short transaction, so no row locks are held across the network calls of
+// delivery; each outcome is recorded on its own afterwards.
+func (ob *OutboxService) DispatchBatch() (int, error) {
+	events, err := ob.claimBatch()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range events {
+		event := &events[i]
+		ob.recordAttempt(event, ob.safeDeliver(event))
+
+		// Events left unrecorded are retried once their claim expires
+		if err := ob.db.Save(event).Error; err != nil {
+			return i, err
+		}
+	}
+
+	return len(events), nil
+}
+
+// claimBatch locks due events with SKIP LOCKED, so several API instances can
+// run dispatchers side by side, and pushes their next attempt ClaimTimeout
+// ahead. Other dispatchers skip them until then, and if this one dies before
+// recording an outcome they are picked up again.
+func (ob *OutboxService) claimBatch() ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := ob.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, time.Now()).
+			Order("id").
+			Limit(ob.cfg.BatchSize).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(events))
+		claimedUntil := time.Now().Add(ob.cfg.ClaimTimeout)
+		for i := range events {
+			ids[i] = events[i].ID
+			events[i].NextAttemptAt = claimedUntil
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", claimedUntil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// safeDeliver delivers an event, turning a panic into a failed attempt so the
+// event backs off and is dead-lettered like any other failure
+func (ob *OutboxService) safeDeliver(event *models.OutboxEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Panic delivering outbox event %d (%s for post %d): %v\n%s",
+				event.ID, event.EventType, event.PostID, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return ob.deliver(event)
+}
+
+// deliver applies a single event. Search events always read the current row
+// from Postgres, so replaying an old event can never resurrect stale data.
+func (ob *OutboxService) deliver(event *models.OutboxEvent) error {
+	switch event.EventType {
+	case models.OutboxEventSearchIndex:
+		var post models.Post
+		err := ob.db.First(&post, event.PostID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ob.searchService.DeletePost(event.PostID)
+		}
+		if err != nil {
+			return err
+		}
+		return ob.searchService.IndexPost(&post)
+	case models.OutboxEventSearchDelete:
+		return ob.searchService.DeletePost(event.PostID)
+	case models.OutboxEventCacheInvalidate:
+		return ob.cacheService.InvalidatePost(event.PostID)
+	default:
+		return fmt.Errorf("unknown outbox event type %q", event.EventType)
+	}
+}
+
+// recordAttempt updates the event after a delivery attempt, scheduling a retry
+// with exponential backoff or moving it to the dead-letter state
+func (ob *OutboxService) recordAttempt(event *models.OutboxEvent, deliveryErr error) {
+	now := time.Now()
+	event.Attempts++
+
+	if deliveryErr == nil {
+		event.Status = models.OutboxStatusDelivered
+		event.DeliveredAt = &now
+		event.LastError = ""
+		return
+	}
+
+	event.LastError = deliveryErr.Error()
+	if event.Attempts >= ob.cfg.MaxAttempts {
+		event.Status = models.OutboxStatusDead
+		log.Printf("Outbox event %d (%s for post %d) moved to dead letter after %d attempts: %v",
+			event.ID, event.EventType, event.PostID, event.Attempts, deliveryErr)
+		return
+	}
+
+	event.NextAttemptAt = now.Add(ob.backoff(event.Attempts))
+	log.Printf("Outbox event %d (%s for post %d) failed, retrying at %s: %v",
+		event.ID, event.EventType, event.PostID, event.NextAttemptAt.Format(time.RFC3339), deliveryErr)
+}
+
+func (ob *OutboxService) backoff(attempts int) time.Duration {
+	delay := ob.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= ob.cfg.MaxBackoff {
+			return ob.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// ListEvents returns outbox events, optionally filtered by status
+func (ob *OutboxService) ListEvents(status string, limit, offset int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	query := ob.db.Model(&models.OutboxEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// RetryEvent puts a dead-lettered event back in the queue for immediate delivery
+func (ob *OutboxService) RetryEvent(id uint) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	if err := ob.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+
+	if event.Status != models.OutboxStatusDead {
+		return nil, ErrOutboxEventNotDead
+	}
+
+	event.Status = models.OutboxStatusPending
+	event.Attempts = 0
+	event.NextAttemptAt = time.Now()
+	if err := ob.db.Save(&event).Error; err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
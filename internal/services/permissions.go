@@ -0,0 +1,161 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Actor identifies who is performing an operation and the request it came
+// from. A nil User is an anonymous caller or a background job.
+type Actor struct {
+	User      *models.User
+	RequestID string
+}
+
+// UserID returns the acting user's ID, or nil for anonymous callers
+func (a Actor) UserID() *uint {
+	if a.User == nil {
+		return nil
+	}
+	id := a.User.ID
+	return &id
+}
+
+func (a Actor) hasRole(roles ...string) bool {
+	if a.User == nil {
+		return false
+	}
+	for _, role := range roles {
+		if a.User.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Actor) owns(post *models.Post) bool {
+	return a.User != nil && post.AuthorID != nil && *post.AuthorID == a.User.ID
+}
+
+// PermissionError explains why an actor may not perform an operation
+type PermissionError struct {
+	Reason string
+}
+
+func (e *PermissionError) Error() string {
+	return e.Reason
+}
+
+func forbidden(reason string) error {
+	return &PermissionError{Reason: reason}
+}
+
+func canCreatePost(actor Actor) error {
+	if actor.User == nil {
+		return forbidden("you must be signed in to create posts")
+	}
+	if !actor.hasRole(models.RoleAdmin, models.RoleEditor, models.RoleAuthor) {
+		return forbidden("readers cannot create posts")
+	}
+	return nil
+}
+
+// canEditPost covers updates, status changes, revision access and restores
+func canEditPost(actor Actor, post *models.Post) error {
+	if actor.User == nil {
+		return forbidden("you must be signed in to edit posts")
+	}
+	if actor.hasRole(models.RoleAdmin, models.RoleEditor) {
+		return nil
+	}
+	if actor.hasRole(models.RoleAuthor) {
+		if actor.owns(post) {
+			return nil
+		}
+		return forbidden("authors can only edit their own posts")
+	}
+	return forbidden("readers cannot edit posts")
+}
+
+func canDeletePost(actor Actor, post *models.Post) error {
+	if actor.User == nil {
+		return forbidden("you must be signed in to delete posts")
+	}
+	if actor.hasRole(models.RoleAdmin, models.RoleEditor) {
+		return nil
+	}
+	if actor.hasRole(models.RoleAuthor) {
+		if actor.owns(post) {
+			return nil
+		}
+		return forbidden("authors can only delete their own posts")
+	}
+	return forbidden("readers cannot delete posts")
+}
+
+// checkPostAccess runs a permission check on a post the actor may see. Posts
+// the actor may not even read are reported missing, as a read would, so a 403
+// never gives a hidden post away.
+func checkPostAccess(actor Actor, post *models.Post, check func(Actor, *models.Post) error) error {
+	if !canViewPost(actor, post) {
+		return ErrPostNotFound
+	}
+	return check(actor, post)
+}
+
+func canManageUsers(actor Actor) error {
+	if !actor.hasRole(models.RoleAdmin) {
+		return forbidden("only admins can manage users")
+	}
+	return nil
+}
+
+func canViewActivity(actor Actor) error {
+	if !actor.hasRole(models.RoleAdmin, models.RoleEditor) {
+		return forbidden("only editors and admins can view the activity log")
+	}
+	return nil
+}
+
+// canSeeAllDrafts reports whether the actor may see every unpublished post
+func canSeeAllDrafts(actor Actor) bool {
+	return actor.hasRole(models.RoleAdmin, models.RoleEditor)
+}
+
+// canSeeOwnDrafts reports whether the actor may see their own unpublished
+// posts. Only authors may: a reader, including an author since demoted,
+// sees published posts alone, even ones they wrote.
+func canSeeOwnDrafts(actor Actor) bool {
+	return actor.hasRole(models.RoleAuthor)
+}
+
+// canViewPost reports whether the actor may read a post in its current status
+func canViewPost(actor Actor, post *models.Post) bool {
+	return post.Status == models.PostStatusPublished || canSeeAllDrafts(actor) ||
+		(canSeeOwnDrafts(actor) && actor.owns(post))
+}
+
+// visibleTo restricts a post query to what the actor may read: published posts,
+// plus their own posts for authors, and everything for editors and admins
+func visibleTo(query *gorm.DB, actor Actor) *gorm.DB {
+	if canSeeAllDrafts(actor) {
+		return query
+	}
+	if canSeeOwnDrafts(actor) {
+		return query.Where("(status = ? OR author_id = ?)", models.PostStatusPublished, actor.User.ID)
+	}
+	return query.Where("status = ?", models.PostStatusPublished)
+}
+
+// searchVisibility is the Elasticsearch equivalent of visibleTo
+func searchVisibility(actor Actor) database.PostVisibility {
+	if canSeeAllDrafts(actor) {
+		return database.PostVisibility{IncludeAllDrafts: true}
+	}
+	if canSeeOwnDrafts(actor) {
+		return database.PostVisibility{DraftsByAuthorID: actor.UserID()}
+	}
+	return database.PostVisibility{}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"blog-api/internal/models"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidPostStatus = errors.New("status must be one of draft, scheduled, published, archived")
+	ErrPublishAtRequired = errors.New("scheduled posts require a publish_at in the future")
+)
+
+// applyStatus moves a post to the requested status and stamps its publish timestamps
+func applyStatus(post *models.Post, status string, publishAt *time.Time) error {
+	now := time.Now()
+
+	switch status {
+	case models.PostStatusDraft, models.PostStatusArchived:
+		post.PublishAt = nil
+	case models.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return ErrPublishAtRequired
+		}
+		post.PublishAt = publishAt
+	case models.PostStatusPublished:
+		post.PublishAt = nil
+		// Keep the original date when a post is re-published
+		if post.PublishedAt == nil {
+			post.PublishedAt = &now
+		}
+	default:
+		return ErrInvalidPostStatus
+	}
+
+	post.Status = status
+	return nil
+}
+
+// IsValidationError reports whether err was caused by invalid client input
+func IsValidationError(err error) bool {
+	return errors.Is(err, ErrInvalidPostStatus) || errors.Is(err, ErrPublishAtRequired) ||
+		errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidSort) || errors.Is(err, ErrInvalidTagMode) ||
+		errors.Is(err, ErrInvalidSearchAfter) || errors.Is(err, ErrSearchTooDeep) || errors.Is(err, ErrInvalidRelatedStrategy)
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"blog-api/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxPostPageSize caps the limit of a single listing page
+	MaxPostPageSize = 100
+
+	// exactCountThreshold is the planner row estimate above which listing totals
+	// are reported as an estimate instead of running COUNT(*)
+	exactCountThreshold = 100_000
+)
+
+var (
+	ErrInvalidCursor  = errors.New("invalid pagination cursor")
+	ErrInvalidSort    = errors.New("sort must be one of created_at, updated_at, published_at, title and order one of asc, desc")
+	ErrInvalidTagMode = errors.New("tag_mode must be one of any, all")
+)
+
+// postSortColumn describes a column posts may be listed by. Every sortable
+// column has a composite index with id so keyset pages stay index scans.
+type postSortColumn struct {
+	text     bool
+	nullable bool
+}
+
+var postSortColumns = map[string]postSortColumn{
+	"created_at":   {},
+	"updated_at":   {},
+	"published_at": {nullable: true},
+	"title":        {text: true},
+}
+
+// Cursor directions
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// postCursor is the keyset position of a listing page boundary. It is sent to
+// clients as opaque base64 so its shape can change without breaking them.
+type postCursor struct {
+	Sort      string  `json:"s"`
+	Order     string  `json:"o"`
+	Value     *string `json:"v"`
+	ID        uint    `json:"i"`
+	Direction string  `json:"d"`
+}
+
+// normalizeListQuery fills in defaults and rejects unknown filter and sort values
+func normalizeListQuery(page *models.PostListQuery) error {
+	if page.Limit <= 0 {
+		page.Limit = 10
+	}
+	if page.Limit > MaxPostPageSize {
+		page.Limit = MaxPostPageSize
+	}
+
+	if page.TagMode == "" {
+		page.TagMode = models.TagModeAny
+	}
+	if page.TagMode != models.TagModeAny && page.TagMode != models.TagModeAll {
+		return ErrInvalidTagMode
+	}
+
+	switch page.Status {
+	case "", models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished, models.PostStatusArchived:
+	default:
+		return ErrInvalidPostStatus
+	}
+
+	if page.Sort == "" {
+		page.Sort = "created_at"
+	}
+	if page.Order == "" {
+		page.Order = models.SortDesc
+	}
+	if _, ok := postSortColumns[page.Sort]; !ok {
+		return ErrInvalidSort
+	}
+	if page.Order != models.SortAsc && page.Order != models.SortDesc {
+		return ErrInvalidSort
+	}
+	return nil
+}
+
+// filterPosts applies the listing filters. Tag filters use the GIN index on
+// tags: "any" is array overlap, "all" is array containment.
+func filterPosts(query *gorm.DB, page *models.PostListQuery) *gorm.DB {
+	if len(page.Tags) > 0 {
+		if page.TagMode == models.TagModeAll {
+			query = query.Where("tags @> ?", pq.Array(page.Tags))
+		} else {
+			query = query.Where("tags && ?", pq.Array(page.Tags))
+		}
+	}
+	if page.AuthorID != nil {
+		query = query.Where("author_id = ?", *page.AuthorID)
+	}
+	if page.Status != "" {
+		query = query.Where("status = ?", page.Status)
+	}
+	if page.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *page.CreatedFrom)
+	}
+	if page.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *page.CreatedTo)
+	}
+	if page.UpdatedFrom != nil {
+		query = query.Where("updated_at >= ?", *page.UpdatedFrom)
+	}
+	if page.UpdatedTo != nil {
+		query = query.Where("updated_at <= ?", *page.UpdatedTo)
+	}
+	return query
+}
+
+// sortValue returns the value of the sort column of a post, nil for NULL
+func sortValue(post *models.Post, sort string) *string {
+	var value string
+	switch sort {
+	case "title":
+		value = post.Title
+	case "updated_at":
+		value = post.UpdatedAt.Format(time.RFC3339Nano)
+	case "published_at":
+		if post.PublishedAt == nil {
+			return nil
+		}
+		value = post.PublishedAt.Format(time.RFC3339Nano)
+	default:
+		value = post.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return &value
+}
+
+func encodeCursor(post *models.Post, page *models.PostListQuery, direction string) string {
+	data, _ := json.Marshal(postCursor{
+		Sort:      page.Sort,
+		Order:     page.Order,
+		Value:     sortValue(post, page.Sort),
+		ID:        post.ID,
+		Direction: direction,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor and checks it was issued for the same sort
+func decodeCursor(value string, page *models.PostListQuery) (*postCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor postCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Direction != cursorNext && cursor.Direction != cursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != page.Sort || cursor.Order != page.Order {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Value != nil && !postSortColumns[cursor.Sort].text {
+		if _, err := time.Parse(time.RFC3339Nano, *cursor.Value); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &cursor, nil
+}
+
+// keysetCondition selects the rows after the cursor in scan order. NULLs of a
+// nullable column always sort last, so they follow every value going forward
+// and precede every value going backwards.
+func keysetCondition(query *gorm.DB, column string, cursor *postCursor, descending, backwards bool) *gorm.DB {
+	op := ">"
+	if descending {
+		op = "<"
+	}
+
+	var value interface{}
+	if cursor.Value != nil {
+		value = *cursor.Value
+		if !postSortColumns[column].text {
+			value, _ = time.Parse(time.RFC3339Nano, *cursor.Value)
+		}
+	}
+
+	rowCompare := fmt.Sprintf("(%s, id) %s (?, ?)", column, op)
+	switch {
+	case !postSortColumns[column].nullable:
+		return query.Where(rowCompare, value, cursor.ID)
+	case cursor.Value == nil && backwards:
+		return query.Where(fmt.Sprintf("(%s IS NOT NULL OR id %s ?)", column, op), cursor.ID)
+	case cursor.Value == nil:
+		return query.Where(fmt.Sprintf("%s IS NULL AND id %s ?", column, op), cursor.ID)
+	case backwards:
+		return query.Where(rowCompare, value, cursor.ID)
+	default:
+		return query.Where(fmt.Sprintf("(%s OR %s IS NULL)", rowCompare, column), value, cursor.ID)
+	}
+}
+
+// paginatePosts loads one page of posts in the requested order, with id as the
+// tie-breaker. A "next" cursor continues after its position, a "prev" cursor
+// returns the page before it; both seek on the sort index instead of OFFSET.
+func paginatePosts(query *gorm.DB, page *models.PostListQuery) (*models.PostListResult, error) {
+	var cursor *postCursor
+	if page.Cursor != "" {
+		decoded, err := decodeCursor(page.Cursor, page)
+		if err != nil {
+			return nil, err
+		}
+		cursor = decoded
+	}
+
+	// Going backwards scans in reverse and flips the page afterwards
+	backwards := cursor != nil && cursor.Direction == cursorPrev
+	descending := (page.Order == models.SortDesc) != backwards
+
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	nulls := ""
+	if postSortColumns[page.Sort].nullable {
+		nulls = " NULLS LAST"
+		if backwards {
+			nulls = " NULLS FIRST"
+		}
+	}
+	query = query.Order(fmt.Sprintf("%s %s%s, id %s", page.Sort, direction, nulls, direction))
+
+	if cursor == nil {
+		query = query.Offset(page.Offset)
+	} else {
+		query = keysetCondition(query, page.Sort, cursor, descending, backwards)
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	var posts []models.Post
+	if err := query.Limit(page.Limit + 1).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := len(posts) > page.Limit
+	if hasMore {
+		posts = posts[:page.Limit]
+	}
+	if backwards {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	result := &models.PostListResult{Posts: posts}
+	if len(posts) == 0 {
+		return result, nil
+	}
+
+	first, last := &posts[0], &posts[len(posts)-1]
+	if backwards {
+		result.NextCursor = encodeCursor(last, page, cursorNext)
+		if hasMore {
+			result.PrevCursor = encodeCursor(first, page, cursorPrev)
+		}
+	} else {
+		if hasMore {
+			result.NextCursor = encodeCursor(last, page, cursorNext)
+		}
+		if cursor != nil || page.Offset > 0 {
+			result.PrevCursor = encodeCursor(first, page, cursorPrev)
+		}
+	}
+	return result, nil
+}
+
+// countPosts returns the number of rows matched by query. Large results are
+// estimated from the query plan so listing never scans the whole table.
+func countPosts(db *gorm.DB, query *gorm.DB) (int64, bool, error) {
+	estimate, err := estimateRows(db, query)
+	if err == nil && estimate > exactCountThreshold {
+		return estimate, true, nil
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, false, err
+	}
+	return total, false, nil
+}
+
+// estimateRows asks the planner how many rows query would return
+func estimateRows(db *gorm.DB, query *gorm.DB) (int64, error) {
+	statement := query.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("id").Find(&[]models.Post{})
+	})
+
+	var plan string
+	if err := db.Raw("EXPLAIN (FORMAT JSON) " + statement).Row().Scan(&plan); err != nil {
+		return 0, err
+	}
+
+	var explained []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.NewDecoder(strings.NewReader(plan)).Decode(&explained); err != nil {
+		return 0, err
+	}
+	if len(explained) == 0 {
+		return 0, errors.New("empty query plan")
+	}
+	return int64(explained[0].Plan.Rows), nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"blog-api/internal/models"
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestNormalizeListQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    models.PostListQuery
+		want    models.PostListQuery
+		wantErr error
+	}{
+		{
+			name: "defaults",
+			page: models.PostListQuery{},
+			want: models.PostListQuery{Limit: 10, TagMode: models.TagModeAny, Sort: "created_at", Order: models.SortDesc},
+		},
+		{
+			name: "limit capped",
+			page: models.PostListQuery{Limit: 1000, Sort: "title", Order: models.SortAsc},
+			want: models.PostListQuery{Limit: MaxPostPageSize, TagMode: models.TagModeAny, Sort: "title", Order: models.SortAsc},
+		},
+		{name: "unknown sort", page: models.PostListQuery{Sort: "id"}, wantErr: ErrInvalidSort},
+		{name: "unknown order", page: models.PostListQuery{Order: "up"}, wantErr: ErrInvalidSort},
+		{name: "unknown tag mode", page: models.PostListQuery{TagMode: "some"}, wantErr: ErrInvalidTagMode},
+		{name: "unknown status", page: models.PostListQuery{Status: "deleted"}, wantErr: ErrInvalidPostStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := tt.page
+			err := normalizeListQuery(&page)
+			if err != tt.wantErr {
+				t.Fatalf("normalizeListQuery error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(page, tt.want) {
+				t.Errorf("normalizeListQuery = %+v, want %+v", page, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 30, 0, 123456789, time.UTC)
+	post := &models.Post{ID: 42, Title: "Bài viết", CreatedAt: created}
+
+	tests := []struct {
+		name      string
+		sort      string
+		wantValue *string
+	}{
+		{"timestamp", "created_at", strPtr(created.Format(time.RFC3339Nano))},
+		{"text", "title", strPtr("Bài viết")},
+		{"null published_at", "published_at", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &models.PostListQuery{Sort: tt.sort, Order: models.SortDesc}
+			cursor, err := decodeCursor(encodeCursor(post, page, cursorNext), page)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if cursor.ID != post.ID || cursor.Direction != cursorNext {
+				t.Errorf("cursor = %+v, want id %d going %s", cursor, post.ID, cursorNext)
+			}
+			if (cursor.Value == nil) != (tt.wantValue == nil) || cursor.Value != nil && *cursor.Value != *tt.wantValue {
+				t.Errorf("cursor value = %v, want %v", cursor.Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	page := &models.PostListQuery{Sort: "created_at", Order: models.SortDesc}
+	post := &models.Post{ID: 1, CreatedAt: time.Now()}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "***"},
+		{"not json", encode("nope")},
+		{"unknown direction", encode(`{"s":"created_at","o":"desc","v":null,"i":1,"d":"up"}`)},
+		{"other sort", encodeCursor(post, &models.PostListQuery{Sort: "updated_at", Order: models.SortDesc}, cursorNext)},
+		{"other order", encodeCursor(post, &models.PostListQuery{Sort: "created_at", Order: models.SortAsc}, cursorNext)},
+		{"bad timestamp", encode(`{"s":"created_at","o":"desc","v":"yesterday","i":1,"d":"next"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, page); err != ErrInvalidCursor {
+				t.Errorf("decodeCursor error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := "2024-05-01T10:30:00Z"
+	title := "Go"
+	tests := []struct {
+		name       string
+		column     string
+		cursor     postCursor
+		descending bool
+		backwards  bool
+		want       string
+	}{
+		{
+			name:   "ascending",
+			column: "created_at", cursor: postCursor{Value: &value, ID: 7},
+			want: `WHERE (created_at, id) > ('2024-05-01 10:30:00', 7)`,
+		},
+		{
+			name:   "descending",
+			column: "created_at", cursor: postCursor{Value: &value, ID: 7}, descending: true,
+			want: `WHERE (created_at, id) < ('2024-05-01 10:30:00', 7)`,
+		},
+		{
+			name:   "text column",
+			column: "title", cursor: postCursor{Value: &title, ID: 7},
+			want: `WHERE (title, id) > ('Go', 7)`,
+		},
+		{
+			name:   "nullable forwards from a value also reaches the nulls",
+			column: "published_at", cursor: postCursor{Value: &value, ID: 7}, descending: true,
+			want: `WHERE ((published_at, id) < ('2024-05-01 10:30:00', 7) OR published_at IS NULL)`,
+		},
+		{
+			name:   "nullable backwards from a value stays among values",
+			column: "published_at", cursor: postCursor{Value: &value, ID: 7}, backwards: true,
+			want: `WHERE (published_at, id) > ('2024-05-01 10:30:00', 7)`,
+		},
+		{
+			name:   "nullable forwards from a null stays among nulls",
+			column: "published_at", cursor: postCursor{ID: 7}, descending: true,
+			want: `WHERE published_at IS NULL AND id < 7`,
+		},
+		{
+			name:   "nullable backwards from a null reaches the values",
+			column: "published_at", cursor: postCursor{ID: 7}, backwards: true,
+			want: `WHERE (published_at IS NOT NULL OR id > 7)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := tt.cursor
+			sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				return keysetCondition(tx.Model(&models.Post{}), tt.column, &cursor, tt.descending, tt.backwards).
+					Find(&[]models.Post{})
+			})
+			want := `SELECT * FROM "posts" ` + tt.want
+			if sql != want {
+				t.Errorf("keysetCondition SQL =\n%s\nwant\n%s", sql, want)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
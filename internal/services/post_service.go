@@ -1,235 +1,543 @@
-package services
-
-import (
-	"blog-api/internal/database"
-	"blog-api/internal/models"
-	"log"
-
-	"github.com/lib/pq"
-	"gorm.io/gorm"
-)
-
-type PostService struct {
-	db           *gorm.DB
-	cacheService *CacheService
-	searchService *SearchService
-}
-
-func NewPostService() *PostService {
-	return &PostService{
-		db:            database.GetDB(),
-		cacheService:  NewCacheService(),
-		searchService: NewSearchService(),
-	}
-}
-
-// CreatePost creates a new post with transaction for data integrity
-func (ps *PostService) CreatePost(req *models.CreatePostRequest) (*models.Post, error) {
-	// Start transaction
-	tx := ps.db.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Create post
-	post := &models.Post{
-		Title:   req.Title,
-		Content: req.Content,
-		Tags:    pq.StringArray(req.Tags),
-	}
-
-	if err := tx.Create(post).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	// Create activity log
-	activityLog := &models.ActivityLog{
-		Action: "new_post",
-		PostID: post.ID,
-	}
-
-	if err := tx.Create(activityLog).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
-	}
-
-	// Index post in Elasticsearch (async - don't fail if this fails)
-	go func() {
-		if err := ps.searchService.IndexPost(post); err != nil {
-			log.Printf("Failed to index post %d: %v", post.ID, err)
-		}
-	}()
-
-	return post, nil
-}
-
-// GetPostByID retrieves a post by ID with Cache-Aside pattern
-func (ps *PostService) GetPostByID(id uint) (*models.PostResponse, error) {
-	// Try to get from cache first
-	cachedPost, err := ps.cacheService.GetPost(id)
-	if err != nil {
-		log.Printf("Error getting post from cache: %v", err)
-	}
-
-	if cachedPost != nil {
-		log.Printf("Cache hit for post %d", id)
-		response := &models.PostResponse{
-			ID:        cachedPost.ID,
-			Title:     cachedPost.Title,
-			Content:   cachedPost.Content,
-			Tags:      cachedPost.Tags,
-			CreatedAt: cachedPost.CreatedAt,
-			UpdatedAt: cachedPost.UpdatedAt,
-		}
-
-		// Get related posts (bonus feature)
-		if len(cachedPost.Tags) > 0 {
-			relatedPosts, _ := ps.searchService.FindRelatedPosts(cachedPost.Tags, id, 5)
-			response.RelatedPosts = relatedPosts
-		}
-
-		return response, nil
-	}
-
-	log.Printf("Cache miss for post %d", id)
-
-	// Get from database
-	var post models.Post
-	if err := ps.db.First(&post, id).Error; err != nil {
-		return nil, err
-	}
-
-	// Store in cache
-	go func() {
-		if err := ps.cacheService.SetPost(&post); err != nil {
-			log.Printf("Error storing post in cache: %v", err)
-		}
-	}()
-
-	response := &models.PostResponse{
-		ID:        post.ID,
-		Title:     post.Title,
-		Content:   post.Content,
-		Tags:      post.Tags,
-		CreatedAt: post.CreatedAt,
-		UpdatedAt: post.UpdatedAt,
-	}
-
-	// Get related posts (bonus feature)
-	if len(post.Tags) > 0 {
-		relatedPosts, _ := ps.searchService.FindRelatedPosts(post.Tags, id, 5)
-		response.RelatedPosts = relatedPosts
-	}
-
-	return response, nil
-}
-
-// UpdatePost updates a post and handles cache invalidation
-func (ps *PostService) UpdatePost(id uint, req *models.UpdatePostRequest) (*models.Post, error) {
-	var post models.Post
-	if err := ps.db.First(&post, id).Error; err != nil {
-		return nil, err
-	}
-
-	// Update fields if provided
-	if req.Title != "" {
-		post.Title = req.Title
-	}
-	if req.Content != "" {
-		post.Content = req.Content
-	}
-	if req.Tags != nil {
-		post.Tags = pq.StringArray(req.Tags)
-	}
-
-	// Save to database
-	if err := ps.db.Save(&post).Error; err != nil {
-		return nil, err
-	}
-
-	// Invalidate cache
-	go func() {
-		if err := ps.cacheService.InvalidatePost(id); err != nil {
-			log.Printf("Error invalidating cache for post %d: %v", id, err)
-		}
-	}()
-
-	// Update Elasticsearch index
-	go func() {
-		if err := ps.searchService.IndexPost(&post); err != nil {
-			log.Printf("Failed to update post %d in search index: %v", post.ID, err)
-		}
-	}()
-
-	return &post, nil
-}
-
-// DeletePost deletes a post and cleans up cache and search index
-func (ps *PostService) DeletePost(id uint) error {
-	if err := ps.db.Delete(&models.Post{}, id).Error; err != nil {
-		return err
-	}
-
-	// Invalidate cache
-	go func() {
-		if err := ps.cacheService.InvalidatePost(id); err != nil {
-			log.Printf("Error invalidating cache for post %d: %v", id, err)
-		}
-	}()
-
-	// Remove from search index
-	go func() {
-		if err := ps.searchService.DeletePost(id); err != nil {
-			log.Printf("Failed to delete post %d from search index: %v", id, err)
-		}
-	}()
-
-	return nil
-}
-
-// SearchPostsByTag searches posts by tag using GIN index
-func (ps *PostService) SearchPostsByTag(tag string) ([]models.Post, error) {
-	var posts []models.Post
-	
-	// Use PostgreSQL's array contains operator with GIN index
-	if err := ps.db.Where("tags @> ?", pq.Array([]string{tag})).Find(&posts).Error; err != nil {
-		return nil, err
-	}
-
-	return posts, nil
-}
-
-// SearchPosts performs full-text search using Elasticsearch
-func (ps *PostService) SearchPosts(query string) (*models.SearchResponse, error) {
-	posts, err := ps.searchService.SearchPosts(query)
-	if err != nil {
-		return nil, err
-	}
-
-	response := &models.SearchResponse{
-		Posts: posts,
-		Total: int64(len(posts)),
-	}
-
-	return response, nil
-}
-
-// GetAllPosts retrieves all posts with pagination
-func (ps *PostService) GetAllPosts(limit, offset int) ([]models.Post, error) {
-	var posts []models.Post
-	if err := ps.db.Limit(limit).Offset(offset).Order("created_at DESC").Find(&posts).Error; err != nil {
-		return nil, err
-	}
-	return posts, nil
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// ErrPostNotFound is returned when a post doesn't exist or the caller may not
+// read it
+var ErrPostNotFound = errors.New("post not found")
+
+type PostService struct {
+	db              *gorm.DB
+	cacheService    *CacheService
+	searchService   *SearchService
+	searchAnalytics *SearchAnalyticsService
+}
+
+func NewPostService() *PostService {
+	return &PostService{
+		db:              database.GetDB(),
+		cacheService:    NewCacheService(),
+		searchService:   NewSearchService(),
+		searchAnalytics: NewSearchAnalyticsService(),
+	}
+}
+
+// CreatePost creates a new post with transaction for data integrity
+func (ps *PostService) CreatePost(actor Actor, req *models.CreatePostRequest) (*models.Post, error) {
+	if err := canCreatePost(actor); err != nil {
+		return nil, err
+	}
+
+	var post *models.Post
+	err := retryOnSlugConflict(func() error {
+		var err error
+		post, err = ps.createPost(actor, req)
+		return err
+	})
+	return post, err
+}
+
+// createPost runs one attempt at CreatePost
+func (ps *PostService) createPost(actor Actor, req *models.CreatePostRequest) (*models.Post, error) {
+	// Start transaction
+	tx := ps.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Create post
+	post := &models.Post{
+		Title:    req.Title,
+		Content:  req.Content,
+		Tags:     pq.StringArray(req.Tags),
+		AuthorID: actor.UserID(),
+	}
+
+	// New posts start as drafts unless a status is requested
+	status := req.Status
+	if status == "" {
+		status = models.PostStatusDraft
+	}
+	if err := applyStatus(post, status, req.PublishAt); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	slug, err := uniqueSlug(tx, post.Title, 0)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	post.Slug = slug
+
+	if err := tx.Create(post).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Create activity log
+	if err := logActivity(tx, actor, models.ActivityNewPost, post.ID, nil, post); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Posts created as published show up in the feed like any other publish
+	if post.Status == models.PostStatusPublished {
+		if err := logActivity(tx, actor, models.ActivityPublishPost, post.ID, nil, post); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Record the initial revision
+	if err := recordRevision(tx, post, nil); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Queue search indexing and listing cache invalidation in the same
+	// transaction (delivered by the outbox dispatcher)
+	if err := EnqueuePostEvents(tx, post.ID, models.OutboxEventCacheInvalidate, models.OutboxEventSearchIndex); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	refreshListings(ps.cacheService)
+
+	// Readers probing the next ID may have left a tombstone for it. The outbox
+	// clears it as well, but that can lag behind the response.
+	if err := ps.cacheService.ClearPostMissing(post.ID); err != nil {
+		log.Printf("Error clearing tombstone of post %d: %v", post.ID, err)
+	}
+
+	return post, nil
+}
+
+// GetPostByID retrieves a post by ID with Cache-Aside pattern, along with
+// posts related to it by the given strategy (DefaultRelatedStrategy if empty)
+func (ps *PostService) GetPostByID(actor Actor, id uint, related string) (*models.PostResponse, error) {
+	if related != "" && !validRelatedStrategy(related) {
+		return nil, ErrInvalidRelatedStrategy
+	}
+
+	// Try to get from cache first
+	cachedPost, err := ps.cacheService.GetPost(id)
+	if err != nil {
+		log.Printf("Error getting post from cache: %v", err)
+	}
+
+	if cachedPost != nil {
+		log.Printf("Cache hit for post %d", id)
+		if !canViewPost(actor, cachedPost) {
+			return nil, ErrPostNotFound
+		}
+
+		response := &models.PostResponse{
+			ID:          cachedPost.ID,
+			Title:       cachedPost.Title,
+			Slug:        cachedPost.Slug,
+			Content:     cachedPost.Content,
+			Tags:        cachedPost.Tags,
+			AuthorID:    cachedPost.AuthorID,
+			Status:      cachedPost.Status,
+			PublishAt:   cachedPost.PublishAt,
+			PublishedAt: cachedPost.PublishedAt,
+			CreatedAt:   cachedPost.CreatedAt,
+			UpdatedAt:   cachedPost.UpdatedAt,
+		}
+
+		// Get related posts (bonus feature)
+		relatedPosts, _ := ps.searchService.FindRelatedPosts(cachedPost, related, 5, actor)
+		response.RelatedPosts = relatedPosts
+
+		return response, nil
+	}
+
+	log.Printf("Cache miss for post %d", id)
+
+	post, err := ps.loadPost(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unpublished posts look missing to callers who may not read them
+	if !canViewPost(actor, post) {
+		return nil, ErrPostNotFound
+	}
+
+	response := &models.PostResponse{
+		ID:          post.ID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Content:     post.Content,
+		Tags:        post.Tags,
+		AuthorID:    post.AuthorID,
+		Status:      post.Status,
+		PublishAt:   post.PublishAt,
+		PublishedAt: post.PublishedAt,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+	}
+
+	// Get related posts (bonus feature)
+	relatedPosts, _ := ps.searchService.FindRelatedPosts(post, related, 5, actor)
+	response.RelatedPosts = relatedPosts
+
+	return response, nil
+}
+
+// postLoads coalesces concurrent cache misses for the same post in this
+// process into a single load
+var postLoads singleflight.Group
+
+// loadPost reads a post after a cache miss and caches it, or caches a
+// tombstone if there is none. Concurrent misses in this process share one
+// load, and across instances only the holder of the post's Redis lock queries
+// Postgres while the others wait for the cache.
+func (ps *PostService) loadPost(id uint) (*models.Post, error) {
+	loaded, err, _ := postLoads.Do(strconv.FormatUint(uint64(id), 10), func() (interface{}, error) {
+		missing, err := ps.cacheService.IsPostMissing(id)
+		if err != nil {
+			log.Printf("Error getting post tombstone from cache: %v", err)
+		}
+		if missing {
+			return nil, ErrPostNotFound
+		}
+
+		token, err := ps.cacheService.AcquirePostLock(id)
+		if err != nil {
+			log.Printf("Error acquiring cache lock for post %d: %v", id, err)
+		} else if token == "" {
+			post, err := ps.cacheService.WaitForPost(id)
+			if errors.Is(err, ErrPostNotFound) {
+				return nil, err
+			}
+			if err != nil {
+				log.Printf("Error getting post from cache: %v", err)
+			}
+			if post != nil {
+				return post, nil
+			}
+		} else {
+			defer func() {
+				if err := ps.cacheService.ReleasePostLock(id, token); err != nil {
+					log.Printf("Error releasing cache lock for post %d: %v", id, err)
+				}
+			}()
+		}
+
+		// Get from database
+		var post models.Post
+		if err := ps.db.First(&post, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := ps.cacheService.SetPostMissing(id); err != nil {
+					log.Printf("Error storing post tombstone in cache: %v", err)
+				}
+				return nil, ErrPostNotFound
+			}
+			return nil, err
+		}
+
+		// Store in cache before the lock is released, so waiters find it
+		if err := ps.cacheService.SetPost(&post); err != nil {
+			log.Printf("Error storing post in cache: %v", err)
+		}
+		return &post, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers share the loaded post, so each gets its own copy
+	post := *loaded.(*models.Post)
+	return &post, nil
+}
+
+// GetPostBySlug resolves a slug to a post. When the slug is an old one, the
+// post's current slug is returned instead so the caller can redirect.
+func (ps *PostService) GetPostBySlug(actor Actor, slug string, related string) (*models.PostResponse, string, error) {
+	var post models.Post
+	err := ps.db.Select("id").Where("slug = ?", slug).First(&post).Error
+	if err == nil {
+		response, err := ps.GetPostByID(actor, post.ID, related)
+		return response, "", err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", err
+	}
+
+	var redirect models.PostSlugRedirect
+	if err := ps.db.Where("slug = ?", slug).First(&redirect).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrPostNotFound
+		}
+		return nil, "", err
+	}
+	if err := ps.db.First(&post, redirect.PostID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrPostNotFound
+		}
+		return nil, "", err
+	}
+
+	// Don't leak the new slug of a post the caller cannot read
+	if !canViewPost(actor, &post) {
+		return nil, "", ErrPostNotFound
+	}
+	return nil, post.Slug, nil
+}
+
+// UpdatePost updates a post, records a revision and handles cache invalidation
+func (ps *PostService) UpdatePost(actor Actor, id uint, req *models.UpdatePostRequest) (*models.Post, error) {
+	var updated *models.Post
+	err := transactionWithSlugRetry(ps.db, func(tx *gorm.DB) error {
+		post, err := lockPost(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkPostAccess(actor, post, canEditPost); err != nil {
+			return err
+		}
+
+		// Posts created before revision history existed get their current state recorded first
+		if err := ensureBaselineRevision(tx, post); err != nil {
+			return err
+		}
+
+		// Update fields if provided
+		before := *post
+		previousTitle := post.Title
+		if req.Title != "" {
+			post.Title = req.Title
+		}
+		if req.Content != "" {
+			post.Content = req.Content
+		}
+		if req.Tags != nil {
+			post.Tags = pq.StringArray(req.Tags)
+		}
+
+		// A bare publish_at reschedules the post
+		status := req.Status
+		if status == "" && req.PublishAt != nil {
+			status = models.PostStatusScheduled
+		}
+		if status != "" {
+			if err := applyStatus(post, status, req.PublishAt); err != nil {
+				return err
+			}
+		}
+
+		if err := syncSlug(tx, post, previousTitle); err != nil {
+			return err
+		}
+
+		// Save the post with its revision, cache invalidation and reindexing atomically
+		if err := savePostChange(tx, post, nil); err != nil {
+			return err
+		}
+
+		if err := logActivity(tx, actor, models.ActivityUpdatePost, post.ID, &before, post); err != nil {
+			return err
+		}
+		if before.Status != models.PostStatusPublished && post.Status == models.PostStatusPublished {
+			if err := logActivity(tx, actor, models.ActivityPublishPost, post.ID, &before, post); err != nil {
+				return err
+			}
+		}
+		updated = post
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshListings(ps.cacheService)
+
+	// Drop stale local copies on every replica now; the outbox clears Redis
+	publishInvalidation(ps.cacheService, id)
+
+	return updated, nil
+}
+
+// DeletePost deletes a post and queues cache and search index cleanup
+func (ps *PostService) DeletePost(actor Actor, id uint) error {
+	err := ps.db.Transaction(func(tx *gorm.DB) error {
+		post, err := lockPost(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkPostAccess(actor, post, canDeletePost); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(post).Error; err != nil {
+			return err
+		}
+		if err := logActivity(tx, actor, models.ActivityDeletePost, id, post, nil); err != nil {
+			return err
+		}
+		return EnqueuePostEvents(tx, id, models.OutboxEventCacheInvalidate, models.OutboxEventSearchDelete)
+	})
+	if err != nil {
+		return err
+	}
+
+	refreshListings(ps.cacheService)
+	publishInvalidation(ps.cacheService, id)
+	return nil
+}
+
+// refreshListings bumps the listing generation once a post change has
+// committed, so cached listings never outlive it. The outbox bumps it again
+// when it invalidates the post, as a backstop.
+func refreshListings(cs *CacheService) {
+	if err := cs.BumpListGeneration(); err != nil {
+		log.Printf("Error bumping listing cache generation: %v", err)
+	}
+}
+
+// publishInvalidation tells every instance to drop a changed post from its
+// local cache. A failure only delays that until the outbox invalidates the
+// post again or the local entry expires.
+func publishInvalidation(cs *CacheService, id uint) {
+	if err := cs.PublishPostInvalidation(id); err != nil {
+		log.Printf("Error publishing cache invalidation of post %d: %v", id, err)
+	}
+}
+
+// SearchPostsByTag searches posts by tag using GIN index, caching the result
+// per visibility until the next post change
+func (ps *PostService) SearchPostsByTag(actor Actor, tag string) ([]models.Post, error) {
+	var cacheKey string
+	if generation, err := ps.cacheService.ListGeneration(); err != nil {
+		log.Printf("Error reading listing cache generation: %v", err)
+	} else {
+		cacheKey = TagPostsCacheKey(generation, visibilityScope(searchVisibility(actor)), tag)
+		cached, err := ps.cacheService.GetTagPosts(cacheKey)
+		if err != nil {
+			log.Printf("Error getting tag posts from cache: %v", err)
+		}
+		if cached != nil {
+			return cached, nil
+		}
+	}
+
+	var posts []models.Post
+
+	// Use PostgreSQL's array contains operator with GIN index
+	query := visibleTo(ps.db, actor)
+	if err := query.Where("tags @> ?", pq.Array([]string{tag})).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	if cacheKey != "" {
+		if err := ps.cacheService.SetTagPosts(cacheKey, posts); err != nil {
+			log.Printf("Error storing tag posts in cache: %v", err)
+		}
+	}
+
+	return posts, nil
+}
+
+// SearchPosts performs full-text search using Elasticsearch. The first page
+// of each search is logged for analytics and its ID returned as search_id.
+func (ps *PostService) SearchPosts(actor Actor, search *models.SearchQuery) (*models.SearchResponse, error) {
+	started := time.Now()
+	response, err := ps.searchService.SearchPosts(search, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if search.Page == 1 && search.SearchAfter == "" {
+		searchID, err := ps.searchAnalytics.RecordSearch(actor, search.Query, response, time.Since(started))
+		if err != nil {
+			log.Printf("Error recording search: %v", err)
+		}
+		response.SearchID = searchID
+	}
+	return response, nil
+}
+
+// RecordSearchClick records the result a reader opened from a search
+func (ps *PostService) RecordSearchClick(click *models.SearchClickRequest) error {
+	return ps.searchAnalytics.RecordClick(click)
+}
+
+// SuggestPosts returns title and tag completions for the search box
+func (ps *PostService) SuggestPosts(actor Actor, prefix string, size int) (*models.SuggestResponse, error) {
+	return ps.searchService.SuggestPosts(prefix, size, actor)
+}
+
+// GetAllPosts lists the posts visible to the actor with the requested filters
+// and sort. Pages are addressed by an opaque cursor; the legacy offset is
+// still honoured when no cursor is given. Pages are cached per visibility
+// until the next post change.
+func (ps *PostService) GetAllPosts(actor Actor, page *models.PostListQuery) (*models.PostListResult, error) {
+	if err := normalizeListQuery(page); err != nil {
+		return nil, err
+	}
+
+	var cacheKey string
+	if generation, err := ps.cacheService.ListGeneration(); err != nil {
+		log.Printf("Error reading listing cache generation: %v", err)
+	} else if cacheKey, err = PostListCacheKey(generation, visibilityScope(searchVisibility(actor)), page); err != nil {
+		log.Printf("Error building listing cache key: %v", err)
+	} else {
+		cached, err := ps.cacheService.GetPostList(cacheKey)
+		if err != nil {
+			log.Printf("Error getting listing from cache: %v", err)
+		}
+		if cached != nil {
+			return cached, nil
+		}
+	}
+
+	// Status filters only narrow what visibleTo already allows
+	listing := func() *gorm.DB {
+		return filterPosts(visibleTo(ps.db.Model(&models.Post{}), actor), page)
+	}
+
+	result, err := paginatePosts(listing(), page)
+	if err != nil {
+		return nil, err
+	}
+
+	total, estimated, err := countPosts(ps.db, listing())
+	if err != nil {
+		return nil, err
+	}
+	result.Total = total
+	result.TotalEstimated = estimated
+
+	if cacheKey != "" {
+		if err := ps.cacheService.SetPostList(cacheKey, result); err != nil {
+			log.Printf("Error storing listing in cache: %v", err)
+		}
+	}
+
+	return result, nil
 }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// publishBatchSize limits how many scheduled posts are published per transaction
+const publishBatchSize = 100
+
+type PublishScheduler struct {
+	db           *gorm.DB
+	cfg          *config.SchedulerConfig
+	cacheService *CacheService
+}
+
+func NewPublishScheduler(cfg *config.SchedulerConfig) *PublishScheduler {
+	return &PublishScheduler{
+		db:           database.GetDB(),
+		cfg:          cfg,
+		cacheService: NewCacheService(),
+	}
+}
+
+// Run publishes scheduled posts as their publish_at is reached until ctx is cancelled
+func (sch *PublishScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(sch.cfg.PublishInterval)
+	defer ticker.Stop()
+
+	log.Printf("Publish scheduler started (interval %s)", sch.cfg.PublishInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Publish scheduler stopped")
+			return
+		case <-ticker.C:
+			for {
+				published, err := sch.PublishDuePosts()
+				if err != nil {
+					log.Printf("Error publishing scheduled posts: %v", err)
+					break
+				}
+				if published < publishBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// PublishDuePosts publishes scheduled posts whose publish_at has passed
+func (sch *PublishScheduler) PublishDuePosts() (int, error) {
+	var published []uint
+	err := sch.db.Transaction(func(tx *gorm.DB) error {
+		var posts []models.Post
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, time.Now()).
+			Order("publish_at").
+			Limit(publishBatchSize).
+			Find(&posts).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range posts {
+			post := &posts[i]
+
+			// The post goes live at its scheduled time, not when the scheduler noticed
+			before := *post
+			publishedAt := *post.PublishAt
+			post.Status = models.PostStatusPublished
+			post.PublishedAt = &publishedAt
+			post.PublishAt = nil
+
+			if err := tx.Save(post).Error; err != nil {
+				return err
+			}
+			if err := EnqueuePostEvents(tx, post.ID, models.OutboxEventCacheInvalidate, models.OutboxEventSearchIndex); err != nil {
+				return err
+			}
+			// No user is involved, so the entry has no actor
+			if err := logActivity(tx, Actor{}, models.ActivityPublishPost, post.ID, &before, post); err != nil {
+				return err
+			}
+			log.Printf("Published scheduled post %d", post.ID)
+			published = append(published, post.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(published) > 0 {
+		refreshListings(sch.cacheService)
+	}
+	for _, id := range published {
+		publishInvalidation(sch.cacheService, id)
+	}
+
+	return len(published), nil
+}
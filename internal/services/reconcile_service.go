@@ -0,0 +1,229 @@
+package services
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReconcileReport lists what a consistency check found and, unless it was a
+// dry run, repaired
+type ReconcileReport struct {
+	StartedAt     time.Time     `json:"started_at"`
+	Duration      time.Duration `json:"duration"`
+	DryRun        bool          `json:"dry_run"`
+	PostgresPosts int           `json:"postgres_posts"`
+	IndexedPosts  int           `json:"indexed_posts"`
+	// Posts in Postgres but not in the index, (re)indexed
+	Missing []uint `json:"missing"`
+	// Posts whose indexed updated_at differs from Postgres, reindexed
+	Stale []uint `json:"stale"`
+	// Documents of posts deleted from Postgres, removed from the index
+	Ghosts []uint `json:"ghosts"`
+	// Cache entries of deleted or changed posts, purged
+	CachePurged []uint `json:"cache_purged"`
+}
+
+// Drift counts the problems the check found
+func (r *ReconcileReport) Drift() int {
+	return len(r.Missing) + len(r.Stale) + len(r.Ghosts) + len(r.CachePurged)
+}
+
+type ReconcileService struct {
+	db           *gorm.DB
+	cfg          *config.ReconcileConfig
+	cacheService *CacheService
+}
+
+func NewReconcileService(cfg *config.ReconcileConfig) *ReconcileService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &ReconcileService{
+		db:           database.GetDB(),
+		cfg:          cfg,
+		cacheService: NewCacheService(),
+	}
+}
+
+// Run reconciles on every interval until ctx is cancelled
+func (rs *ReconcileService) Run(ctx context.Context) {
+	ticker := time.NewTicker(rs.cfg.Interval)
+	defer ticker.Stop()
+
+	log.Printf("Reconciler started (interval %s)", rs.cfg.Interval)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Reconciler stopped")
+			return
+		case <-ticker.C:
+			report, err := rs.Reconcile(ctx, false)
+			if err != nil {
+				log.Printf("Error reconciling posts: %v", err)
+				continue
+			}
+			if report.Drift() > 0 {
+				log.Printf("Reconciled posts: %d missing, %d stale, %d ghosts; %d cache entries purged",
+					len(report.Missing), len(report.Stale), len(report.Ghosts), len(report.CachePurged))
+			}
+		}
+	}
+}
+
+// Reconcile compares the posts table, which is the source of truth, with the
+// search index and the post cache, and repairs any drift. The index is read
+// before Postgres so a post created in between shows up as missing (and is
+// indexed again, harmlessly) rather than as a ghost.
+func (rs *ReconcileService) Reconcile(ctx context.Context, dryRun bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		StartedAt:   time.Now(),
+		DryRun:      dryRun,
+		Missing:     []uint{},
+		Stale:       []uint{},
+		Ghosts:      []uint{},
+		CachePurged: []uint{},
+	}
+
+	indexed, err := database.ScanIndexedPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scanning search index: %w", err)
+	}
+	stored, err := rs.postVersions()
+	if err != nil {
+		return nil, fmt.Errorf("reading posts: %w", err)
+	}
+	report.IndexedPosts = len(indexed)
+	report.PostgresPosts = len(stored)
+
+	for id, updatedAt := range stored {
+		indexedAt, ok := indexed[id]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, id)
+		case !sameInstant(indexedAt, updatedAt):
+			report.Stale = append(report.Stale, id)
+		}
+	}
+	for id := range indexed {
+		if _, ok := stored[id]; !ok {
+			report.Ghosts = append(report.Ghosts, id)
+		}
+	}
+
+	cached, err := rs.cacheService.CachedPostIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scanning cache: %w", err)
+	}
+	for _, id := range cached {
+		updatedAt, ok := stored[id]
+		if !ok {
+			report.CachePurged = append(report.CachePurged, id)
+			continue
+		}
+		post, err := rs.cacheService.redisPost(id)
+		if post == nil && err == nil {
+			// Expired since the scan
+			continue
+		}
+		// Unreadable entries are dropped as well
+		if err != nil || !sameInstant(post.UpdatedAt, updatedAt) {
+			report.CachePurged = append(report.CachePurged, id)
+		}
+	}
+
+	if !dryRun {
+		if err := rs.repair(ctx, report); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+func (rs *ReconcileService) repair(ctx context.Context, report *ReconcileReport) error {
+	index := database.PostsWriteAlias()
+
+	// Reload the posts at repair time so the freshest state is indexed
+	reindex := append(append([]uint{}, report.Missing...), report.Stale...)
+	for start := 0; start < len(reindex); start += rs.cfg.BatchSize {
+		end := start + rs.cfg.BatchSize
+		if end > len(reindex) {
+			end = len(reindex)
+		}
+
+		var posts []models.Post
+		if err := rs.db.Where("id IN ?", reindex[start:end]).Find(&posts).Error; err != nil {
+			return err
+		}
+		docs := make(map[string]interface{}, len(posts))
+		for i := range posts {
+			docs[fmt.Sprintf("%d", posts[i].ID)] = toElasticsearchPost(&posts[i])
+		}
+		if err := database.BulkIndexPosts(ctx, index, docs); err != nil {
+			return err
+		}
+	}
+
+	// Check ghosts are still absent right before deleting them; removing the
+	// document of a live post would hide it from search until it is edited
+	if len(report.Ghosts) > 0 {
+		var existing []uint
+		if err := rs.db.Model(&models.Post{}).Where("id IN ?", report.Ghosts).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		skip := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			skip[id] = true
+		}
+
+		var ids []string
+		for _, id := range report.Ghosts {
+			if !skip[id] {
+				ids = append(ids, fmt.Sprintf("%d", id))
+			}
+		}
+		if err := database.BulkDeletePosts(ctx, index, ids); err != nil {
+			return err
+		}
+	}
+
+	// Stale and ghost posts may be cached with the same drift
+	purge := make(map[uint]bool)
+	for _, ids := range [][]uint{report.CachePurged, report.Stale, report.Ghosts} {
+		for _, id := range ids {
+			purge[id] = true
+		}
+	}
+	for id := range purge {
+		if err := rs.cacheService.InvalidatePost(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postVersions returns the updated_at of every post keyed by ID
+func (rs *ReconcileService) postVersions() (map[uint]time.Time, error) {
+	versions := make(map[uint]time.Time)
+	var posts []models.Post
+	result := rs.db.Select("id", "updated_at").FindInBatches(&posts, rs.cfg.BatchSize, func(tx *gorm.DB, batch int) error {
+		for _, post := range posts {
+			versions[post.ID] = post.UpdatedAt
+		}
+		return nil
+	})
+	return versions, result.Error
+}
+
+// sameInstant compares timestamps at the microsecond precision Postgres keeps
+func sameInstant(a, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
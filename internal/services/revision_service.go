@@ -0,0 +1,194 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevisionService struct {
+	db           *gorm.DB
+	cacheService *CacheService
+}
+
+func NewRevisionService() *RevisionService {
+	return &RevisionService{
+		db:           database.GetDB(),
+		cacheService: NewCacheService(),
+	}
+}
+
+// recordRevision snapshots the post as its next revision inside the caller's transaction
+func recordRevision(tx *gorm.DB, post *models.Post, restoredFrom *int) error {
+	var latest int
+	if err := tx.Model(&models.PostRevision{}).
+		Where("post_id = ?", post.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	revision := &models.PostRevision{
+		PostID:       post.ID,
+		Version:      latest + 1,
+		Title:        post.Title,
+		Content:      post.Content,
+		Tags:         append(pq.StringArray{}, post.Tags...),
+		RestoredFrom: restoredFrom,
+	}
+	return tx.Create(revision).Error
+}
+
+// ensureBaselineRevision records the current state of a post created before
+// revisions existed, so its first edit can still be diffed and undone
+func ensureBaselineRevision(tx *gorm.DB, post *models.Post) error {
+	var count int64
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordRevision(tx, post, nil)
+}
+
+// savePostChange persists an edited post together with its revision and outbox events.
+// The caller must hold a row lock on the post so revision numbers stay sequential.
+func savePostChange(tx *gorm.DB, post *models.Post, restoredFrom *int) error {
+	if err := tx.Save(post).Error; err != nil {
+		return err
+	}
+	if err := recordRevision(tx, post, restoredFrom); err != nil {
+		return err
+	}
+	return EnqueuePostEvents(tx, post.ID, models.OutboxEventCacheInvalidate, models.OutboxEventSearchIndex)
+}
+
+// lockPost loads a post with a row lock for the rest of the transaction
+func lockPost(tx *gorm.DB, id uint) (*models.Post, error) {
+	var post models.Post
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, id).Error; err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// authorize checks that the actor may work with the revisions of a post
+func (rs *RevisionService) authorize(actor Actor, postID uint) error {
+	var post models.Post
+	if err := rs.db.First(&post, postID).Error; err != nil {
+		return err
+	}
+	return checkPostAccess(actor, &post, canEditPost)
+}
+
+// ListRevisions returns all revisions of a post, newest first
+func (rs *RevisionService) ListRevisions(actor Actor, postID uint) ([]models.PostRevision, error) {
+	if err := rs.authorize(actor, postID); err != nil {
+		return nil, err
+	}
+
+	var revisions []models.PostRevision
+	if err := rs.db.Where("post_id = ?", postID).Order("version DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetRevision returns a single revision of a post
+func (rs *RevisionService) GetRevision(actor Actor, postID uint, version int) (*models.PostRevision, error) {
+	if err := rs.authorize(actor, postID); err != nil {
+		return nil, err
+	}
+	return rs.findRevision(postID, version)
+}
+
+func (rs *RevisionService) findRevision(postID uint, version int) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	if err := rs.db.Where("post_id = ? AND version = ?", postID, version).First(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// DiffRevisions compares two revisions of a post
+func (rs *RevisionService) DiffRevisions(actor Actor, postID uint, from, to int, mode string) (*models.RevisionDiffResponse, error) {
+	if err := rs.authorize(actor, postID); err != nil {
+		return nil, err
+	}
+
+	fromRevision, err := rs.findRevision(postID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := rs.findRevision(postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != DiffModeWord {
+		mode = DiffModeLine
+	}
+
+	added, removed := diffTags(fromRevision.Tags, toRevision.Tags)
+	return &models.RevisionDiffResponse{
+		PostID:      postID,
+		From:        from,
+		To:          to,
+		Mode:        mode,
+		Title:       DiffText(fromRevision.Title, toRevision.Title, DiffModeWord),
+		Content:     DiffText(fromRevision.Content, toRevision.Content, mode),
+		TagsAdded:   added,
+		TagsRemoved: removed,
+	}, nil
+}
+
+// RestoreRevision copies an old revision back onto the post, recorded as a new revision
+func (rs *RevisionService) RestoreRevision(actor Actor, postID uint, version int) (*models.Post, error) {
+	var restored *models.Post
+	err := transactionWithSlugRetry(rs.db, func(tx *gorm.DB) error {
+		post, err := lockPost(tx, postID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkPostAccess(actor, post, canEditPost); err != nil {
+			return err
+		}
+
+		var revision models.PostRevision
+		if err := tx.Where("post_id = ? AND version = ?", postID, version).First(&revision).Error; err != nil {
+			return err
+		}
+
+		before := *post
+		previousTitle := post.Title
+		post.Title = revision.Title
+		post.Content = revision.Content
+		post.Tags = append(pq.StringArray{}, revision.Tags...)
+
+		if err := syncSlug(tx, post, previousTitle); err != nil {
+			return err
+		}
+
+		if err := savePostChange(tx, post, &revision.Version); err != nil {
+			return err
+		}
+		if err := logActivity(tx, actor, models.ActivityRestorePost, post.ID, &before, post); err != nil {
+			return err
+		}
+		restored = post
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshListings(rs.cacheService)
+	publishInvalidation(rs.cacheService, postID)
+
+	return restored, nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxSavedSearchesPerUser bounds the percolator queries one user can add
+	maxSavedSearchesPerUser = 20
+
+	// maxNotificationPageSize caps GET /me/notifications responses
+	maxNotificationPageSize = 100
+)
+
+var (
+	ErrTooManySavedSearches = fmt.Errorf("a user can save at most %d searches", maxSavedSearchesPerUser)
+	ErrAlertsUnavailable    = errors.New("search alerts are unavailable while Elasticsearch is down")
+)
+
+type SavedSearchService struct {
+	db *gorm.DB
+}
+
+func NewSavedSearchService() *SavedSearchService {
+	return &SavedSearchService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateSavedSearch saves a search for the actor and registers its query in
+// the percolator. The row is rolled back if registration fails, so every saved
+// search can raise alerts.
+func (ss *SavedSearchService) CreateSavedSearch(actor Actor, req *models.CreateSavedSearchRequest) (*models.SavedSearch, error) {
+	if actor.User == nil {
+		return nil, forbidden("you must be signed in to save searches")
+	}
+
+	savedSearch := &models.SavedSearch{
+		UserID: actor.User.ID,
+		Name:   strings.TrimSpace(req.Name),
+		Query:  strings.TrimSpace(req.Query),
+		Tags:   pq.StringArray(req.Tags),
+	}
+
+	err := ss.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.SavedSearch{}).Where("user_id = ?", actor.User.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxSavedSearchesPerUser {
+			return ErrTooManySavedSearches
+		}
+
+		if err := tx.Create(savedSearch).Error; err != nil {
+			return err
+		}
+		if err := database.RegisterSavedSearch(savedSearch.ID, savedSearch.UserID, savedSearch.Query, savedSearch.Tags); err != nil {
+			log.Printf("Error registering saved search %d: %v", savedSearch.ID, err)
+			return ErrAlertsUnavailable
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return savedSearch, nil
+}
+
+// ListSavedSearches returns the actor's saved searches, newest first
+func (ss *SavedSearchService) ListSavedSearches(actor Actor) ([]models.SavedSearch, error) {
+	if actor.User == nil {
+		return nil, forbidden("you must be signed in to list saved searches")
+	}
+
+	savedSearches := []models.SavedSearch{}
+	err := ss.db.Where("user_id = ?", actor.User.ID).Order("created_at DESC, id DESC").Find(&savedSearches).Error
+	return savedSearches, err
+}
+
+// DeleteSavedSearch removes one of the actor's saved searches along with its
+// percolator query and notifications. Other users' searches look missing.
+func (ss *SavedSearchService) DeleteSavedSearch(actor Actor, id uint) error {
+	if actor.User == nil {
+		return forbidden("you must be signed in to delete saved searches")
+	}
+
+	return ss.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, actor.User.ID).Delete(&models.SavedSearch{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("saved_search_id = ?", id).Delete(&models.SearchNotification{}).Error; err != nil {
+			return err
+		}
+		if err := database.UnregisterSavedSearch(id); err != nil {
+			log.Printf("Error unregistering saved search %d: %v", id, err)
+			return ErrAlertsUnavailable
+		}
+		return nil
+	})
+}
+
+// ListNotifications returns the actor's notifications, newest first
+func (ss *SavedSearchService) ListNotifications(actor Actor, unreadOnly bool, limit, offset int) ([]models.SearchNotification, error) {
+	if actor.User == nil {
+		return nil, forbidden("you must be signed in to read notifications")
+	}
+	if limit <= 0 || limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
+	}
+
+	query := ss.db.Where("user_id = ?", actor.User.ID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	notifications := []models.SearchNotification{}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkNotificationRead marks one of the actor's notifications as read
+func (ss *SavedSearchService) MarkNotificationRead(actor Actor, id uint) error {
+	if actor.User == nil {
+		return forbidden("you must be signed in to read notifications")
+	}
+
+	result := ss.db.Model(&models.SearchNotification{}).
+		Where("id = ? AND user_id = ?", id, actor.User.ID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// notifySavedSearches runs a published post through the percolator and
+// notifies the owners of the saved searches it matches. Searches saved after
+// the post was published and the author's own searches are skipped.
+// Notifications already sent are left alone, so indexing a post again is safe.
+func notifySavedSearches(db *gorm.DB, post *models.Post) error {
+	if post.Status != models.PostStatusPublished {
+		return nil
+	}
+
+	ids, err := database.PercolatePost(toElasticsearchPost(post))
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	query := db.Where("id IN ?", ids)
+	if post.PublishedAt != nil {
+		query = query.Where("created_at <= ?", *post.PublishedAt)
+	}
+	if post.AuthorID != nil {
+		query = query.Where("user_id <> ?", *post.AuthorID)
+	}
+	var savedSearches []models.SavedSearch
+	if err := query.Find(&savedSearches).Error; err != nil {
+		return err
+	}
+	if len(savedSearches) == 0 {
+		return nil
+	}
+
+	notifications := make([]models.SearchNotification, 0, len(savedSearches))
+	for _, savedSearch := range savedSearches {
+		notifications = append(notifications, models.SearchNotification{
+			UserID:        savedSearch.UserID,
+			SavedSearchID: savedSearch.ID,
+			PostID:        post.ID,
+			PostTitle:     post.Title,
+			PostSlug:      post.Slug,
+		})
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Post %d matched %d saved searches", post.ID, result.RowsAffected)
+	}
+	return nil
+}
+
+// registerSavedSearches registers every saved search in the percolator again
+// and returns how many there are
+func registerSavedSearches(ctx context.Context, db *gorm.DB, batchSize int) (int, error) {
+	registered := 0
+	var savedSearches []models.SavedSearch
+	result := db.WithContext(ctx).FindInBatches(&savedSearches, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, savedSearch := range savedSearches {
+			if err := database.RegisterSavedSearch(savedSearch.ID, savedSearch.UserID, savedSearch.Query, savedSearch.Tags); err != nil {
+				return err
+			}
+		}
+		registered += len(savedSearches)
+		return nil
+	})
+	return registered, result.Error
+}
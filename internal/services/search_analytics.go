@@ -0,0 +1,157 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAnalyticsRows caps the queries listed by a report
+	maxAnalyticsRows = 100
+
+	// maxNormalizedQueryLength matches the normalized_query column
+	maxNormalizedQueryLength = 255
+)
+
+var ErrSearchLogNotFound = errors.New("search not found")
+
+type SearchAnalyticsService struct {
+	db *gorm.DB
+}
+
+func NewSearchAnalyticsService() *SearchAnalyticsService {
+	return &SearchAnalyticsService{
+		db: database.GetDB(),
+	}
+}
+
+// normalizeSearchQuery folds a query the way the search index does, so "Bài
+// Viết" and "bai viet" are reported as one query: diacritics are stripped,
+// letters lowercased and punctuation and repeated spaces collapsed
+func normalizeSearchQuery(query string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range norm.NFD.String(query) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ' || r == 'Đ':
+			r = 'd'
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		} else if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+
+	normalized := strings.TrimSpace(b.String())
+	if len(normalized) > maxNormalizedQueryLength {
+		normalized = strings.ToValidUTF8(normalized[:maxNormalizedQueryLength], "")
+	}
+	return normalized
+}
+
+// RecordSearch logs a search and returns its ID, which clients send back when
+// a result is clicked
+func (sa *SearchAnalyticsService) RecordSearch(actor Actor, query string, response *models.SearchResponse, latency time.Duration) (uint, error) {
+	searchLog := &models.SearchLog{
+		Query:           query,
+		NormalizedQuery: normalizeSearchQuery(query),
+		ResultCount:     response.Total,
+		Engine:          response.Engine,
+		LatencyMS:       latency.Milliseconds(),
+		UserID:          actor.UserID(),
+		RequestID:       actor.RequestID,
+	}
+	if err := sa.db.Create(searchLog).Error; err != nil {
+		return 0, err
+	}
+	return searchLog.ID, nil
+}
+
+// RecordClick stores the post a reader opened from a search. Only the first
+// click counts, so click-through rates are per search rather than per click.
+func (sa *SearchAnalyticsService) RecordClick(click *models.SearchClickRequest) error {
+	result := sa.db.Model(&models.SearchLog{}).
+		Where("id = ? AND clicked_at IS NULL", click.SearchID).
+		Updates(map[string]interface{}{
+			"clicked_post_id": click.PostID,
+			"clicked_at":      time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := sa.db.Model(&models.SearchLog{}).Where("id = ?", click.SearchID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSearchLogNotFound
+	}
+	return nil
+}
+
+// TopQueries lists the most searched queries in the window
+func (sa *SearchAnalyticsService) TopQueries(filter *models.SearchAnalyticsFilter) ([]models.SearchQueryStats, error) {
+	return sa.queryStats(filter, "", "searches DESC, query")
+}
+
+// ZeroResultQueries lists the queries that most often found nothing
+func (sa *SearchAnalyticsService) ZeroResultQueries(filter *models.SearchAnalyticsFilter) ([]models.SearchQueryStats, error) {
+	return sa.queryStats(filter, "count(*) FILTER (WHERE result_count = 0) > 0", "zero_results DESC, searches DESC, query")
+}
+
+// ClickThroughRates lists queries by click-through rate, lowest first, so
+// popular queries whose results readers ignore come out on top
+func (sa *SearchAnalyticsService) ClickThroughRates(filter *models.SearchAnalyticsFilter) ([]models.SearchQueryStats, error) {
+	return sa.queryStats(filter, "", "click_through_rate, searches DESC, query")
+}
+
+// queryStats aggregates the searches of the window per normalized query
+func (sa *SearchAnalyticsService) queryStats(filter *models.SearchAnalyticsFilter, having, order string) ([]models.SearchQueryStats, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > maxAnalyticsRows {
+		limit = maxAnalyticsRows
+	}
+	minSearches := filter.MinSearches
+	if minSearches < 1 {
+		minSearches = 1
+	}
+
+	query := sa.db.Model(&models.SearchLog{}).
+		Select(`normalized_query AS query,
+			count(*) AS searches,
+			count(*) FILTER (WHERE result_count = 0) AS zero_results,
+			count(clicked_at) AS clicks,
+			count(clicked_at)::float / count(*) AS click_through_rate,
+			avg(result_count) AS avg_results,
+			avg(latency_ms) AS avg_latency_ms,
+			max(searched_at) AS last_searched_at`).
+		Where("searched_at >= ? AND searched_at < ?", filter.From, filter.To).
+		Group("normalized_query").
+		Having("count(*) >= ?", minSearches)
+	if having != "" {
+		query = query.Having(having)
+	}
+
+	stats := []models.SearchQueryStats{}
+	if err := query.Order(order).Limit(limit).Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
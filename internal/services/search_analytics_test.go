@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalizeSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"lowercased", "Golang API", "golang api"},
+		{"diacritics folded", "Bài Viết", "bai viet"},
+		{"d with stroke", "Đường đi", "duong di"},
+		{"punctuation collapsed", "go, redis & es!", "go redis es"},
+		{"spaces collapsed and trimmed", "  go    api  ", "go api"},
+		{"digits kept", "Go 1.21", "go 1 21"},
+		{"only punctuation", "?!", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeSearchQuery(tt.query); got != tt.want {
+				t.Errorf("normalizeSearchQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSearchQueryTruncatesToValidUTF8(t *testing.T) {
+	// Non-ASCII letters that survive folding are multi-byte, so a byte cut can
+	// land inside one
+	query := "a" + strings.Repeat("ж", maxNormalizedQueryLength)
+
+	got := normalizeSearchQuery(query)
+	if len(got) > maxNormalizedQueryLength {
+		t.Errorf("len = %d, want at most %d", len(got), maxNormalizedQueryLength)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("normalizeSearchQuery returned invalid UTF-8 %q", got)
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"encoding/json"
+	"strings"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// The Postgres backend searches the generated search_vector column (see
+// migrations/011) when Elasticsearch is disabled or failing. It supports the
+// same filters, facets and paging; highlights come from ts_headline and there
+// are no spelling suggestions. Matching is accent-sensitive, since Postgres
+// has no Vietnamese configuration.
+
+// pgSearchHit is a post matched by searchPostgres with its rank and highlights
+type pgSearchHit struct {
+	models.Post
+	Rank             float64
+	TitleHighlight   string
+	ContentHighlight string
+}
+
+// searchPostgres serves SearchPosts from Postgres. after holds the rank and ID
+// of the last hit of the previous page, as handed out in next_search_after.
+func (ss *SearchService) searchPostgres(search *models.SearchQuery, after *searchAfterToken, actor Actor) (*models.SearchResponse, error) {
+	// matching builds a fresh query of the visible posts matching the text,
+	// with the query available as "query" for ranking and highlighting
+	matching := func() *gorm.DB {
+		query := ss.db.Model(&models.Post{}).
+			Joins("CROSS JOIN websearch_to_tsquery('simple', ?) AS query", search.Query).
+			Where("search_vector @@ query")
+		return visibleTo(query, actor)
+	}
+	tagFilter := func(query *gorm.DB) *gorm.DB {
+		if len(search.Tags) > 0 {
+			query = query.Where("tags @> ?", pq.Array(search.Tags))
+		}
+		return query
+	}
+	dateFilter := func(query *gorm.DB) *gorm.DB {
+		if search.From != nil {
+			query = query.Where("created_at >= ?", *search.From)
+		}
+		if search.To != nil {
+			query = query.Where("created_at <= ?", *search.To)
+		}
+		return query
+	}
+
+	response := &models.SearchResponse{
+		Engine: models.SearchEnginePostgres,
+		Posts:  []models.Post{},
+		Hits:   []models.SearchHit{},
+		Page:   search.Page,
+		Size:   search.Size,
+		Facets: &models.SearchFacets{
+			Tags:   []models.FacetBucket{},
+			Months: []models.FacetBucket{},
+		},
+	}
+
+	if err := matching().Scopes(tagFilter, dateFilter).Count(&response.Total).Error; err != nil {
+		return nil, err
+	}
+
+	hitsQuery := matching().Scopes(tagFilter, dateFilter).
+		Select(`posts.*,
+			ts_rank(search_vector, query) AS rank,
+			ts_headline('simple', title, query, 'StartSel=<em>, StopSel=</em>, HighlightAll=true') AS title_highlight,
+			ts_headline('simple', content, query, 'StartSel=<em>, StopSel=</em>, MaxFragments=3, MaxWords=25, MinWords=10') AS content_highlight`).
+		Order("rank DESC, id DESC").
+		Limit(search.Size)
+	if after != nil {
+		rank, id, err := pgSearchAfter(after)
+		if err != nil {
+			return nil, err
+		}
+		hitsQuery = hitsQuery.Where("(ts_rank(search_vector, query), id) < (?, ?)", rank, id)
+	} else {
+		hitsQuery = hitsQuery.Offset((search.Page - 1) * search.Size)
+	}
+
+	var hits []pgSearchHit
+	if err := hitsQuery.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	for _, hit := range hits {
+		searchHit := models.SearchHit{Post: hit.Post, Score: hit.Rank, Highlights: map[string][]string{}}
+		// ts_headline returns the start of the text when nothing matched it
+		if strings.Contains(hit.TitleHighlight, "<em>") {
+			searchHit.Highlights["title"] = []string{hit.TitleHighlight}
+		}
+		if strings.Contains(hit.ContentHighlight, "<em>") {
+			searchHit.Highlights["content"] = strings.Split(hit.ContentHighlight, " ... ")
+		}
+		response.Posts = append(response.Posts, hit.Post)
+		response.Hits = append(response.Hits, searchHit)
+	}
+	if len(hits) == search.Size {
+		last := hits[len(hits)-1]
+		response.NextSearchAfter = encodeSearchAfter(models.SearchEnginePostgres, []interface{}{last.Rank, last.ID})
+	}
+
+	// Like the Elasticsearch aggregations, each facet ignores its own filter
+	err := matching().Scopes(dateFilter).
+		Joins("CROSS JOIN unnest(tags) AS tag").
+		Select("tag AS value, count(*) AS count").
+		Group("tag").
+		Order("count DESC, tag").
+		Limit(20).
+		Scan(&response.Facets.Tags).Error
+	if err != nil {
+		return nil, err
+	}
+	err = matching().Scopes(tagFilter).
+		Select("to_char(created_at, 'YYYY-MM') AS value, count(*) AS count").
+		Group("value").
+		Order("value").
+		Scan(&response.Facets.Months).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// pgSearchAfter reads the rank and post ID of a search_after token. Tokens
+// handed out by Elasticsearch are rejected, their sort values differ.
+func pgSearchAfter(after *searchAfterToken) (float64, uint64, error) {
+	if after.Engine != models.SearchEnginePostgres || len(after.Sort) != 2 {
+		return 0, 0, ErrInvalidSearchAfter
+	}
+	rankNumber, ok := after.Sort[0].(json.Number)
+	if !ok {
+		return 0, 0, ErrInvalidSearchAfter
+	}
+	idNumber, ok := after.Sort[1].(json.Number)
+	if !ok {
+		return 0, 0, ErrInvalidSearchAfter
+	}
+	rank, err := rankNumber.Float64()
+	if err != nil {
+		return 0, 0, ErrInvalidSearchAfter
+	}
+	id, err := idNumber.Int64()
+	if err != nil || id < 0 {
+		return 0, 0, ErrInvalidSearchAfter
+	}
+	return rank, uint64(id), nil
+}
+
+// suggestPostgres is the Postgres fallback of SuggestPosts. Titles have to
+// start with the prefix; the completion suggester also matches later words.
+func (ss *SearchService) suggestPostgres(prefix string, size int, actor Actor) (*models.SuggestResponse, error) {
+	pattern := escapeLike(prefix) + "%"
+	response := &models.SuggestResponse{
+		Posts: []models.PostSuggestion{},
+		Tags:  []models.FacetBucket{},
+	}
+
+	err := visibleTo(ss.db.Model(&models.Post{}), actor).
+		Select("id, title, slug").
+		Where("title ILIKE ?", pattern).
+		Order("created_at DESC").
+		Limit(size).
+		Scan(&response.Posts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = visibleTo(ss.db.Model(&models.Post{}), actor).
+		Joins("CROSS JOIN unnest(tags) AS tag").
+		Select("tag AS value, count(*) AS count").
+		Where("tag ILIKE ?", pattern).
+		Group("tag").
+		Order("count DESC, tag").
+		Limit(size).
+		Scan(&response.Tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// relatedPostsPostgres is the Postgres fallback of FindRelatedPosts. Shared
+// tags stand in for tag overlap and any word of the post's title for
+// more_like_this; there is no recency decay beyond the tie-break.
+func (ss *SearchService) relatedPostsPostgres(post *models.Post, strategy string, limit int, actor Actor) ([]models.Post, error) {
+	query := ss.db.Model(&models.Post{}).
+		Joins("CROSS JOIN websearch_to_tsquery('simple', array_to_string(tsvector_to_array(to_tsvector('simple', ?)), ' or ')) AS query", post.Title).
+		Where("id <> ?", post.ID)
+	query = visibleTo(query, actor)
+
+	const overlap = "cardinality(ARRAY(SELECT unnest(tags) INTERSECT SELECT unnest(?::text[])))"
+	switch strategy {
+	case database.RelatedByTags:
+		query = query.Select("posts.*, "+overlap+" AS score", pq.Array(post.Tags)).
+			Where("tags && ?", pq.Array(post.Tags))
+	case database.RelatedByContent:
+		query = query.Select("posts.*, ts_rank(search_vector, query) AS score").
+			Where("search_vector @@ query")
+	default:
+		query = query.Select("posts.*, "+overlap+" + ts_rank(search_vector, query) AS score", pq.Array(post.Tags)).
+			Where("(tags && ? OR search_vector @@ query)", pq.Array(post.Tags))
+	}
+
+	posts := []models.Post{}
+	err := query.Order("score DESC, created_at DESC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
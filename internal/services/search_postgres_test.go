@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"golang", "golang"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`C:\go`, `C:\\go`},
+		{`%_\`, `\%\_\\`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// catchUpMargin is subtracted from the reindex start time when looking for
+// changes made during the copy, to absorb clock skew between servers
+const catchUpMargin = time.Minute
+
+// ReindexReport summarizes a reindex run
+type ReindexReport struct {
+	Index           string
+	PreviousIndices []string
+	Indexed         int
+	CaughtUp        int
+	Deleted         int
+	SavedSearches   int
+	Duration        time.Duration
+}
+
+type ReindexService struct {
+	db        *gorm.DB
+	batchSize int
+}
+
+func NewReindexService(batchSize int) *ReindexService {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &ReindexService{
+		db:        database.GetDB(),
+		batchSize: batchSize,
+	}
+}
+
+// Reindex rebuilds the posts index without downtime: every post is streamed
+// from Postgres into a new versioned index while the aliases keep serving the
+// old one, then the aliases are swapped atomically. Changes written to the
+// old index during the copy are replayed from Postgres afterwards, and every
+// saved search is registered in the percolator again.
+func (rs *ReindexService) Reindex(ctx context.Context, deleteOld bool) (*ReindexReport, error) {
+	started := time.Now()
+	report := &ReindexReport{}
+
+	index, err := database.CreatePostsIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Index = index
+
+	report.Indexed, err = rs.copyPosts(ctx, index, rs.db.Model(&models.Post{}))
+	if err != nil {
+		return nil, fmt.Errorf("copying posts to %s: %w", index, err)
+	}
+	log.Printf("Copied %d posts to %s", report.Indexed, index)
+
+	report.PreviousIndices, err = database.SwapPostsAliases(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+
+	// Writes now reach the new index; replay what changed while copying
+	since := started.Add(-catchUpMargin)
+	report.CaughtUp, err = rs.copyPosts(ctx, index, rs.db.Model(&models.Post{}).Where("updated_at >= ?", since))
+	if err != nil {
+		return nil, fmt.Errorf("catching up updated posts: %w", err)
+	}
+	report.Deleted, err = rs.removeDeletedPosts(ctx, index, since)
+	if err != nil {
+		return nil, fmt.Errorf("catching up deleted posts: %w", err)
+	}
+
+	// The percolator is rebuilt from scratch after its index is deleted
+	report.SavedSearches, err = registerSavedSearches(ctx, rs.db, rs.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("registering saved searches: %w", err)
+	}
+
+	if deleteOld {
+		for _, old := range report.PreviousIndices {
+			if old == index {
+				continue
+			}
+			if err := database.DeletePostsIndex(ctx, old); err != nil {
+				return nil, err
+			}
+			log.Printf("Deleted old index %s", old)
+		}
+	}
+
+	report.Duration = time.Since(started)
+	return report, nil
+}
+
+// copyPosts streams the posts matched by query into index with the bulk API
+func (rs *ReindexService) copyPosts(ctx context.Context, index string, query *gorm.DB) (int, error) {
+	copied := 0
+	var posts []models.Post
+	result := query.FindInBatches(&posts, rs.batchSize, func(tx *gorm.DB, batch int) error {
+		docs := make(map[string]interface{}, len(posts))
+		for i := range posts {
+			docs[fmt.Sprintf("%d", posts[i].ID)] = toElasticsearchPost(&posts[i])
+		}
+		if err := database.BulkIndexPosts(ctx, index, docs); err != nil {
+			return err
+		}
+		copied += len(posts)
+		return nil
+	})
+	return copied, result.Error
+}
+
+// removeDeletedPosts drops posts deleted since the copy started, which the
+// copy may have picked up before they were deleted
+func (rs *ReindexService) removeDeletedPosts(ctx context.Context, index string, since time.Time) (int, error) {
+	var postIDs []uint
+	err := rs.db.Model(&models.ActivityLog{}).
+		Where("action = ? AND logged_at >= ?", models.ActivityDeletePost, since).
+		Distinct().
+		Pluck("post_id", &postIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(postIDs))
+	for _, id := range postIDs {
+		ids = append(ids, fmt.Sprintf("%d", id))
+	}
+	return len(ids), database.BulkDeletePosts(ctx, index, ids)
+}
@@ -1,109 +1,459 @@
-package services
-
-import (
-	"blog-api/internal/database"
-	"blog-api/internal/models"
-	"encoding/json"
-	"fmt"
-	"log"
-)
-
-type SearchService struct {
-}
-
-func NewSearchService() *SearchService {
-	return &SearchService{}
-}
-
-// IndexPost indexes a post in Elasticsearch
-func (ss *SearchService) IndexPost(post *models.Post) error {
-	esPost := models.ElasticsearchPost{
-		ID:      post.ID,
-		Title:   post.Title,
-		Content: post.Content,
-		Tags:    post.Tags,
-	}
-
-	err := database.IndexPost(esPost, fmt.Sprintf("%d", post.ID))
-	if err != nil {
-		log.Printf("Error indexing post %d: %v", post.ID, err)
-		return err
-	}
-
-	log.Printf("Post %d indexed successfully", post.ID)
-	return nil
-}
-
-// DeletePost removes a post from Elasticsearch index
-func (ss *SearchService) DeletePost(id uint) error {
-	err := database.DeletePost(fmt.Sprintf("%d", id))
-	if err != nil {
-		log.Printf("Error deleting post %d from index: %v", id, err)
-		return err
-	}
-
-	log.Printf("Post %d deleted from index successfully", id)
-	return nil
-}
-
-// SearchPosts performs full-text search on posts
-func (ss *SearchService) SearchPosts(query string) ([]models.Post, error) {
-	searchResult, err := database.SearchPosts(query)
-	if err != nil {
-		log.Printf("Error searching posts: %v", err)
-		return nil, err
-	}
-
-	var posts []models.Post
-	for _, hit := range searchResult.Hits.Hits {
-		var esPost models.ElasticsearchPost
-		err := json.Unmarshal(hit.Source, &esPost)
-		if err != nil {
-			log.Printf("Error unmarshaling search result: %v", err)
-			continue
-		}
-
-		post := models.Post{
-			ID:      esPost.ID,
-			Title:   esPost.Title,
-			Content: esPost.Content,
-			Tags:    esPost.Tags,
-		}
-		posts = append(posts, post)
-	}
-
-	return posts, nil
-}
-
-// FindRelatedPosts finds posts with similar tags
-func (ss *SearchService) FindRelatedPosts(tags []string, excludeID uint, limit int) ([]models.Post, error) {
-	if len(tags) == 0 {
-		return []models.Post{}, nil
-	}
-
-	searchResult, err := database.FindRelatedPosts(tags, excludeID, limit)
-	if err != nil {
-		log.Printf("Error finding related posts: %v", err)
-		return nil, err
-	}
-
-	var posts []models.Post
-	for _, hit := range searchResult.Hits.Hits {
-		var esPost models.ElasticsearchPost
-		err := json.Unmarshal(hit.Source, &esPost)
-		if err != nil {
-			log.Printf("Error unmarshaling related post: %v", err)
-			continue
-		}
-
-		post := models.Post{
-			ID:      esPost.ID,
-			Title:   esPost.Title,
-			Content: esPost.Content,
-			Tags:    esPost.Tags,
-		}
-		posts = append(posts, post)
-	}
-
-	return posts, nil
-}
\ No newline at end of file
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/olivere/elastic/v7"
+	"gorm.io/gorm"
+)
+
+const (
+	maxSearchPageSize = 100
+	maxSuggestSize    = 10
+
+	// maxSearchWindow matches Elasticsearch's default index.max_result_window;
+	// deeper pages have to use search_after
+	maxSearchWindow = 10000
+)
+
+var (
+	ErrInvalidSearchAfter     = errors.New("invalid search_after token")
+	ErrSearchTooDeep          = fmt.Errorf("page is beyond the first %d results, use search_after to go deeper", maxSearchWindow)
+	ErrInvalidRelatedStrategy = fmt.Errorf("related must be one of %s, %s or %s", database.RelatedByTags, database.RelatedByContent, database.RelatedHybrid)
+)
+
+// DefaultRelatedStrategy is used when a request does not pick one
+const DefaultRelatedStrategy = database.RelatedHybrid
+
+type SearchService struct {
+	db           *gorm.DB
+	cacheService *CacheService
+}
+
+func NewSearchService() *SearchService {
+	return &SearchService{
+		db:           database.GetDB(),
+		cacheService: NewCacheService(),
+	}
+}
+
+// IndexPost indexes a post in Elasticsearch and, once it is published,
+// notifies the saved searches it matches. It does nothing when Elasticsearch
+// is disabled, since posts are then searched in Postgres.
+func (ss *SearchService) IndexPost(post *models.Post) error {
+	if database.ElasticsearchDisabled() {
+		return nil
+	}
+
+	err := database.IndexPost(toElasticsearchPost(post), fmt.Sprintf("%d", post.ID))
+	if err != nil {
+		log.Printf("Error indexing post %d: %v", post.ID, err)
+		return err
+	}
+
+	if err := notifySavedSearches(ss.db, post); err != nil {
+		log.Printf("Error percolating post %d: %v", post.ID, err)
+		return err
+	}
+
+	log.Printf("Post %d indexed successfully", post.ID)
+	return nil
+}
+
+// DeletePost removes a post from Elasticsearch index
+func (ss *SearchService) DeletePost(id uint) error {
+	if database.ElasticsearchDisabled() {
+		return nil
+	}
+
+	err := database.DeletePost(fmt.Sprintf("%d", id))
+	if err != nil {
+		log.Printf("Error deleting post %d from index: %v", id, err)
+		return err
+	}
+
+	log.Printf("Post %d deleted from index successfully", id)
+	return nil
+}
+
+// SearchPosts performs full-text search on posts and returns one page of
+// scored, highlighted hits. Postgres serves the search when Elasticsearch is
+// disabled or fails.
+func (ss *SearchService) SearchPosts(search *models.SearchQuery, actor Actor) (*models.SearchResponse, error) {
+	if search.Size <= 0 {
+		search.Size = 10
+	}
+	if search.Size > maxSearchPageSize {
+		search.Size = maxSearchPageSize
+	}
+	if search.Page <= 0 {
+		search.Page = 1
+	}
+
+	params := database.PostSearch{
+		Query:       search.Query,
+		Tags:        search.Tags,
+		CreatedFrom: search.From,
+		CreatedTo:   search.To,
+		Size:        search.Size,
+	}
+	var after *searchAfterToken
+	if search.SearchAfter != "" {
+		decoded, err := decodeSearchAfter(search.SearchAfter)
+		if err != nil {
+			return nil, err
+		}
+		after = decoded
+	} else {
+		params.From = (search.Page - 1) * search.Size
+		if params.From+search.Size > maxSearchWindow {
+			return nil, ErrSearchTooDeep
+		}
+	}
+
+	if database.ElasticsearchDisabled() {
+		return ss.searchPostgres(search, after, actor)
+	}
+
+	// Sort values of one engine mean nothing to the other
+	if after != nil {
+		if after.Engine != models.SearchEngineElasticsearch {
+			return nil, ErrInvalidSearchAfter
+		}
+		params.SearchAfter = after.Sort
+	}
+
+	searchResult, err := database.SearchPosts(params, searchVisibility(actor))
+	if err != nil {
+		log.Printf("Error searching posts, falling back to PostgreSQL: %v", err)
+		return ss.searchPostgres(search, after, actor)
+	}
+
+	response := &models.SearchResponse{
+		Engine: models.SearchEngineElasticsearch,
+		Posts:  []models.Post{},
+		Hits:   []models.SearchHit{},
+		Page:   search.Page,
+		Size:   search.Size,
+	}
+	if searchResult.Hits == nil {
+		return response, nil
+	}
+	if searchResult.Hits.TotalHits != nil {
+		response.Total = searchResult.Hits.TotalHits.Value
+	}
+	response.Facets = facetsFrom(searchResult)
+
+	for _, hit := range searchResult.Hits.Hits {
+		post, err := postFromHit(hit)
+		if err != nil {
+			log.Printf("Error unmarshaling search result: %v", err)
+			continue
+		}
+
+		searchHit := models.SearchHit{Post: post, Highlights: map[string][]string(hit.Highlight)}
+		if hit.Score != nil {
+			searchHit.Score = *hit.Score
+		}
+		response.Posts = append(response.Posts, post)
+		response.Hits = append(response.Hits, searchHit)
+	}
+
+	// A full page means there may be more; hand out the last hit's sort values
+	hits := searchResult.Hits.Hits
+	if len(hits) == search.Size {
+		response.NextSearchAfter = encodeSearchAfter(models.SearchEngineElasticsearch, hits[len(hits)-1].Sort)
+	}
+
+	if response.Total == 0 {
+		response.Suggestion = spellingSuggestion(searchResult, search.Query)
+		if search.Autocorrect && response.Suggestion != "" {
+			corrected := *search
+			corrected.Query = response.Suggestion
+			corrected.Autocorrect = false
+			correctedResponse, err := ss.SearchPosts(&corrected, actor)
+			if err != nil {
+				return nil, err
+			}
+			correctedResponse.Suggestion = ""
+			correctedResponse.CorrectedFrom = search.Query
+			return correctedResponse, nil
+		}
+	}
+
+	return response, nil
+}
+
+// spellingSuggestion returns the best correction of query, or "" when there
+// is none
+func spellingSuggestion(searchResult *elastic.SearchResult, query string) string {
+	for _, suggestion := range searchResult.Suggest[database.SearchDidYouMean] {
+		for _, option := range suggestion.Options {
+			if option.Text != "" && !strings.EqualFold(option.Text, query) {
+				return option.Text
+			}
+		}
+	}
+	return ""
+}
+
+// FindRelatedPosts finds posts related to post with the given strategy. The
+// results are cached per post, listing generation, strategy and visibility, so
+// any post change refreshes them. Postgres finds them, uncached, when
+// Elasticsearch is disabled or fails.
+func (ss *SearchService) FindRelatedPosts(post *models.Post, strategy string, limit int, actor Actor) ([]models.Post, error) {
+	if strategy == "" {
+		strategy = DefaultRelatedStrategy
+	}
+	if !validRelatedStrategy(strategy) {
+		return nil, ErrInvalidRelatedStrategy
+	}
+	// Matching on tags alone needs tags
+	if strategy == database.RelatedByTags && len(post.Tags) == 0 {
+		return []models.Post{}, nil
+	}
+
+	if database.ElasticsearchDisabled() {
+		return ss.relatedPostsPostgres(post, strategy, limit, actor)
+	}
+
+	visibility := searchVisibility(actor)
+	field := fmt.Sprintf("%s:%d:%s", strategy, limit, visibilityScope(visibility))
+
+	// Without the generation a cached list could outlive changes to the posts
+	// in it, so caching is skipped when it can't be read
+	generation, genErr := ss.cacheService.ListGeneration()
+	if genErr != nil {
+		log.Printf("Error reading listing cache generation: %v", genErr)
+	} else {
+		cached, err := ss.cacheService.GetRelatedPosts(generation, post.ID, field)
+		if err != nil {
+			log.Printf("Error getting related posts from cache: %v", err)
+		}
+		if cached != nil {
+			return cached, nil
+		}
+	}
+
+	searchResult, err := database.FindRelatedPosts(strategy, post.ID, post.Tags, limit, visibility)
+	if err != nil {
+		log.Printf("Error finding related posts, falling back to PostgreSQL: %v", err)
+		return ss.relatedPostsPostgres(post, strategy, limit, actor)
+	}
+
+	posts := []models.Post{}
+	for _, hit := range searchResult.Hits.Hits {
+		post, err := postFromHit(hit)
+		if err != nil {
+			log.Printf("Error unmarshaling related post: %v", err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	if genErr == nil {
+		if err := ss.cacheService.SetRelatedPosts(generation, post.ID, field, posts); err != nil {
+			log.Printf("Error storing related posts in cache: %v", err)
+		}
+	}
+
+	return posts, nil
+}
+
+func validRelatedStrategy(strategy string) bool {
+	switch strategy {
+	case database.RelatedByTags, database.RelatedByContent, database.RelatedHybrid:
+		return true
+	}
+	return false
+}
+
+// visibilityScope names the set of posts a visibility allows, so callers who
+// see the same posts share cache entries
+func visibilityScope(v database.PostVisibility) string {
+	switch {
+	case v.IncludeAllDrafts:
+		return "all"
+	case v.DraftsByAuthorID != nil:
+		return fmt.Sprintf("author-%d", *v.DraftsByAuthorID)
+	default:
+		return "public"
+	}
+}
+
+// SuggestPosts completes a search box prefix with post titles and tags.
+// Postgres completes it when Elasticsearch is disabled or fails.
+func (ss *SearchService) SuggestPosts(prefix string, size int, actor Actor) (*models.SuggestResponse, error) {
+	if size <= 0 || size > maxSuggestSize {
+		size = maxSuggestSize
+	}
+
+	if database.ElasticsearchDisabled() {
+		return ss.suggestPostgres(prefix, size, actor)
+	}
+
+	searchResult, err := database.SuggestPosts(prefix, size, searchVisibility(actor))
+	if err != nil {
+		log.Printf("Error suggesting posts, falling back to PostgreSQL: %v", err)
+		return ss.suggestPostgres(prefix, size, actor)
+	}
+
+	response := &models.SuggestResponse{
+		Posts: []models.PostSuggestion{},
+		Tags:  []models.FacetBucket{},
+	}
+	for _, suggestion := range searchResult.Suggest[database.SuggestTitles] {
+		for _, option := range suggestion.Options {
+			var post models.PostSuggestion
+			if err := json.Unmarshal(option.Source, &post); err != nil {
+				log.Printf("Error unmarshaling suggestion: %v", err)
+				continue
+			}
+			response.Posts = append(response.Posts, post)
+		}
+	}
+	if terms, ok := searchResult.Aggregations.Terms(database.SuggestTags); ok {
+		for _, bucket := range terms.Buckets {
+			response.Tags = append(response.Tags, models.FacetBucket{
+				Value: fmt.Sprint(bucket.Key),
+				Count: bucket.DocCount,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// toElasticsearchPost builds the indexed document of a post
+func toElasticsearchPost(post *models.Post) models.ElasticsearchPost {
+	return models.ElasticsearchPost{
+		ID:          post.ID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Content:     post.Content,
+		Tags:        post.Tags,
+		AuthorID:    post.AuthorID,
+		Status:      post.Status,
+		PublishedAt: post.PublishedAt,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+
+		TitleSuggest: &models.CompletionInput{
+			Input:    titleSuggestInputs(post.Title),
+			Contexts: database.PostSuggestContexts(post.Status, post.AuthorID),
+		},
+	}
+}
+
+// maxSuggestInputs bounds how many word positions of a title can start a
+// completion
+const maxSuggestInputs = 6
+
+// titleSuggestInputs returns the title and its tails starting at each later
+// word, so typing a word from the middle of a title still completes it
+func titleSuggestInputs(title string) []string {
+	words := strings.Fields(title)
+	inputs := make([]string, 0, maxSuggestInputs)
+	for i := 0; i < len(words) && i < maxSuggestInputs; i++ {
+		inputs = append(inputs, strings.Join(words[i:], " "))
+	}
+	return inputs
+}
+
+// postFromHit rebuilds a post from its indexed document
+func postFromHit(hit *elastic.SearchHit) (models.Post, error) {
+	var esPost models.ElasticsearchPost
+	if err := json.Unmarshal(hit.Source, &esPost); err != nil {
+		return models.Post{}, err
+	}
+
+	return models.Post{
+		ID:          esPost.ID,
+		Title:       esPost.Title,
+		Slug:        esPost.Slug,
+		Content:     esPost.Content,
+		Tags:        esPost.Tags,
+		AuthorID:    esPost.AuthorID,
+		Status:      esPost.Status,
+		PublishedAt: esPost.PublishedAt,
+		CreatedAt:   esPost.CreatedAt,
+		UpdatedAt:   esPost.UpdatedAt,
+	}, nil
+}
+
+// facetsFrom reads the tag and month buckets of a search result
+func facetsFrom(searchResult *elastic.SearchResult) *models.SearchFacets {
+	facets := &models.SearchFacets{
+		Tags:   []models.FacetBucket{},
+		Months: []models.FacetBucket{},
+	}
+
+	if facet, ok := searchResult.Aggregations.Filter(database.FacetTags); ok {
+		if terms, ok := facet.Terms("buckets"); ok {
+			for _, bucket := range terms.Buckets {
+				facets.Tags = append(facets.Tags, models.FacetBucket{
+					Value: fmt.Sprint(bucket.Key),
+					Count: bucket.DocCount,
+				})
+			}
+		}
+	}
+
+	if facet, ok := searchResult.Aggregations.Filter(database.FacetMonths); ok {
+		if histogram, ok := facet.DateHistogram("buckets"); ok {
+			for _, bucket := range histogram.Buckets {
+				if bucket.KeyAsString == nil {
+					continue
+				}
+				facets.Months = append(facets.Months, models.FacetBucket{
+					Value: *bucket.KeyAsString,
+					Count: bucket.DocCount,
+				})
+			}
+		}
+	}
+
+	return facets
+}
+
+// searchAfterToken is the position after the last hit of a search page,
+// together with the engine whose sort values it holds
+type searchAfterToken struct {
+	Engine string        `json:"e"`
+	Sort   []interface{} `json:"s"`
+}
+
+// encodeSearchAfter turns the sort values of a hit into an opaque page token
+func encodeSearchAfter(engine string, sort []interface{}) string {
+	data, _ := json.Marshal(searchAfterToken{Engine: engine, Sort: sort})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchAfter(token string) (*searchAfterToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidSearchAfter
+	}
+
+	// Keep numbers exact; a float64 round trip would corrupt large sort values
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var after searchAfterToken
+	if err := decoder.Decode(&after); err != nil || len(after.Sort) == 0 {
+		return nil, ErrInvalidSearchAfter
+	}
+	switch after.Engine {
+	case models.SearchEngineElasticsearch, models.SearchEnginePostgres:
+	default:
+		return nil, ErrInvalidSearchAfter
+	}
+	return &after, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"blog-api/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestSearchAfterRoundTrip(t *testing.T) {
+	token := encodeSearchAfter(models.SearchEnginePostgres, []interface{}{0.0759, uint(9007199254740993)})
+	after, err := decodeSearchAfter(token)
+	if err != nil {
+		t.Fatalf("decodeSearchAfter: %v", err)
+	}
+	rank, id, err := pgSearchAfter(after)
+	if err != nil {
+		t.Fatalf("pgSearchAfter: %v", err)
+	}
+	if rank != 0.0759 || id != 9007199254740993 {
+		t.Errorf("pgSearchAfter = %v, %d, want 0.0759, 9007199254740993", rank, id)
+	}
+}
+
+func TestDecodeSearchAfterRejects(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "***"},
+		{"not json", encode("nope")},
+		{"bare sort values", encode(`[1.5, 3]`)},
+		{"no sort values", encode(`{"e":"postgres","s":[]}`)},
+		{"unknown engine", encode(`{"e":"solr","s":[1.5, 3]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeSearchAfter(tt.token); err != ErrInvalidSearchAfter {
+				t.Errorf("decodeSearchAfter error = %v, want %v", err, ErrInvalidSearchAfter)
+			}
+		})
+	}
+}
+
+func TestPgSearchAfterRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		after searchAfterToken
+	}{
+		{"elasticsearch token", searchAfterToken{Engine: models.SearchEngineElasticsearch, Sort: []interface{}{json.Number("1.5"), json.Number("3")}}},
+		{"wrong length", searchAfterToken{Engine: models.SearchEnginePostgres, Sort: []interface{}{json.Number("1.5")}}},
+		{"rank not a number", searchAfterToken{Engine: models.SearchEnginePostgres, Sort: []interface{}{"high", json.Number("3")}}},
+		{"negative id", searchAfterToken{Engine: models.SearchEnginePostgres, Sort: []interface{}{json.Number("1.5"), json.Number("-3")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := tt.after
+			if _, _, err := pgSearchAfter(&after); err != ErrInvalidSearchAfter {
+				t.Errorf("pgSearchAfter error = %v, want %v", err, ErrInvalidSearchAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSlugLength keeps URLs readable; longer slugs are cut at a word boundary
+	maxSlugLength = 80
+
+	// slugAttempts bounds how often a write is retried after a concurrent
+	// writer claimed the slug it picked
+	slugAttempts = 3
+)
+
+// ErrSlugConflict is returned when a write kept losing its slug to
+// concurrent writers
+var ErrSlugConflict = errors.New("another post with the same title is being saved; please retry")
+
+// Slugify turns a title into a lowercase ASCII slug. Vietnamese diacritics are
+// stripped by decomposing to NFD and dropping combining marks; đ has no
+// decomposition and is mapped by hand.
+func Slugify(title string) string {
+	var b strings.Builder
+	lastHyphen := true
+	for _, r := range norm.NFD.String(title) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ' || r == 'Đ':
+			r = 'd'
+		}
+
+		r = unicode.ToLower(r)
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			lastHyphen = false
+		} else if !lastHyphen {
+			b.WriteByte('-')
+			lastHyphen = true
+		}
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if cut := strings.LastIndexByte(slug, '-'); cut > 0 {
+			slug = slug[:cut]
+		}
+	}
+	if slug == "" {
+		slug = "post"
+	}
+	return slug
+}
+
+// slugTaken reports whether a slug is used by another post, either as its
+// current slug or as one of its redirects
+func slugTaken(tx *gorm.DB, slug string, postID uint) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Post{}).Where("slug = ? AND id <> ?", slug, postID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := tx.Model(&models.PostSlugRedirect{}).Where("slug = ? AND post_id <> ?", slug, postID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// uniqueSlug returns the slug for a title, adding -2, -3... on collisions
+func uniqueSlug(tx *gorm.DB, title string, postID uint) (string, error) {
+	base := Slugify(title)
+	candidate := base
+	for i := 2; ; i++ {
+		taken, err := slugTaken(tx, candidate, postID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// isSlugConflict reports whether err is a unique violation on a slug, which
+// happens when a concurrent writer picked the same free slug
+func isSlugConflict(err error) bool {
+	switch uniqueViolation(err) {
+	case "idx_posts_slug", "idx_post_slug_redirects_slug":
+		return true
+	}
+	return false
+}
+
+// retryOnSlugConflict runs write, which picks a slug with uniqueSlug and saves
+// it in its own transaction, again when the slug was taken in the meantime;
+// the next run sees the winner's slug and moves on to the next suffix
+func retryOnSlugConflict(write func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if !isSlugConflict(err) {
+			return err
+		}
+		if attempt == slugAttempts {
+			return ErrSlugConflict
+		}
+	}
+}
+
+// transactionWithSlugRetry runs fn in a transaction with retryOnSlugConflict
+func transactionWithSlugRetry(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return retryOnSlugConflict(func() error {
+		return db.Transaction(fn)
+	})
+}
+
+// syncSlug regenerates the slug when the title changed (or the post has none
+// yet) and keeps the old slug resolving as a redirect
+func syncSlug(tx *gorm.DB, post *models.Post, previousTitle string) error {
+	if post.Slug != "" && post.Title == previousTitle {
+		return nil
+	}
+
+	slug, err := uniqueSlug(tx, post.Title, post.ID)
+	if err != nil {
+		return err
+	}
+	if slug == post.Slug {
+		return nil
+	}
+
+	if post.Slug != "" {
+		redirect := &models.PostSlugRedirect{Slug: post.Slug, PostID: post.ID}
+		if err := tx.Create(redirect).Error; err != nil {
+			return err
+		}
+	}
+
+	// Going back to an earlier title reclaims its slug from the redirects
+	if err := tx.Where("slug = ? AND post_id = ?", slug, post.ID).Delete(&models.PostSlugRedirect{}).Error; err != nil {
+		return err
+	}
+
+	post.Slug = slug
+	return nil
+}
+
+// BackfillPostSlugs assigns slugs to posts created before slugs existed
+func BackfillPostSlugs() error {
+	db := database.GetDB()
+
+	for {
+		var posts []models.Post
+		if err := db.Where("slug IS NULL OR slug = ''").Order("id").Limit(100).Find(&posts).Error; err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			return nil
+		}
+
+		for i := range posts {
+			post := &posts[i]
+			err := transactionWithSlugRetry(db, func(tx *gorm.DB) error {
+				slug, err := uniqueSlug(tx, post.Title, post.ID)
+				if err != nil {
+					return err
+				}
+				return tx.Model(post).UpdateColumn("slug", slug).Error
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("Assigned slug to post %d", post.ID)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"plain ascii", "Hello World", "hello-world"},
+		{"vietnamese diacritics", "Bài viết đầu tiên", "bai-viet-dau-tien"},
+		{"uppercase d with stroke", "Đà Nẵng", "da-nang"},
+		{"stacked tone marks", "Tiếng Việt thật hay", "tieng-viet-that-hay"},
+		{"punctuation collapses", "Go: tips & tricks!!", "go-tips-tricks"},
+		{"leading and trailing separators", "  --Hello--  ", "hello"},
+		{"digits kept", "Top 10 mẹo Go 1.21", "top-10-meo-go-1-21"},
+		{"non latin dropped", "Xin chào 世界", "xin-chao"},
+		{"nothing left", "!!! ???", "post"},
+		{"empty", "", "post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slugify(tt.title); got != tt.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugifyTruncatesAtWordBoundary(t *testing.T) {
+	title := strings.Repeat("chuyen dai ", 20)
+
+	got := Slugify(title)
+	if len(got) > maxSlugLength {
+		t.Fatalf("len(Slugify) = %d, want at most %d", len(got), maxSlugLength)
+	}
+	if strings.HasSuffix(got, "-") {
+		t.Errorf("Slugify = %q, must not end with a hyphen", got)
+	}
+	if !strings.HasPrefix(title, strings.ReplaceAll(got, "-", " ")) {
+		t.Errorf("Slugify = %q, cut in the middle of a word", got)
+	}
+}
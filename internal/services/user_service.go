@@ -0,0 +1,87 @@
+package services
+
+import (
+	"blog-api/internal/database"
+	"blog-api/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultUserPageSize and MaxUserPageSize bound GET /users pages
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 100
+)
+
+type UserService struct {
+	db *gorm.DB
+}
+
+func NewUserService() *UserService {
+	return &UserService{
+		db: database.GetDB(),
+	}
+}
+
+// UserPageSize returns the page size used for a requested limit
+func UserPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultUserPageSize
+	}
+	if limit > MaxUserPageSize {
+		return MaxUserPageSize
+	}
+	return limit
+}
+
+// ListUsers returns users ordered by ID, at most MaxUserPageSize at a time
+func (us *UserService) ListUsers(actor Actor, limit, offset int) ([]models.User, error) {
+	if err := canManageUsers(actor); err != nil {
+		return nil, err
+	}
+	limit = UserPageSize(limit)
+
+	var users []models.User
+	if err := us.db.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// UpdateRole changes a user's role
+func (us *UserService) UpdateRole(actor Actor, id uint, role string) (*models.User, error) {
+	if err := canManageUsers(actor); err != nil {
+		return nil, err
+	}
+	if actor.User.ID == id && role != models.RoleAdmin {
+		return nil, forbidden("admins cannot remove their own admin role")
+	}
+
+	var user models.User
+	if err := us.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	if err := us.db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GrantAdmin makes the user with the given email an admin. It backs the
+// grant-admin command, which bootstraps the first admin of a deploy, so it
+// checks no actor.
+func (us *UserService) GrantAdmin(email string) (*models.User, error) {
+	var user models.User
+	if err := us.db.Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	user.Role = models.RoleAdmin
+	if err := us.db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
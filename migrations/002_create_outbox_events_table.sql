@@ -0,0 +1,16 @@
+-- Create outbox_events table for Elasticsearch/Redis side effects
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id SERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    post_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+-- Dispatcher polls pending events that are due
+CREATE INDEX IF NOT EXISTS idx_outbox_events_status_next_attempt ON outbox_events (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_post_id ON outbox_events (post_id);
@@ -0,0 +1,14 @@
+-- Create post_revisions table to keep every saved version of a post
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    tags TEXT[] DEFAULT '{}',
+    restored_from INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per version of a post
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revisions_post_version ON post_revisions (post_id, version);
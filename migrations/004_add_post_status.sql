@@ -0,0 +1,10 @@
+-- Add publishing lifecycle to posts. Existing posts stay public.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+UPDATE posts SET published_at = created_at WHERE status = 'published' AND published_at IS NULL;
+
+-- Listings filter on status, the scheduler polls publish_at
+CREATE INDEX IF NOT EXISTS idx_posts_status ON posts (status);
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at);
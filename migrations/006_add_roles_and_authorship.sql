@@ -0,0 +1,8 @@
+-- Roles for users, authorship for posts, actors for activity logs
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'reader';
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id);
+
+ALTER TABLE activity_logs ADD COLUMN IF NOT EXISTS user_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_activity_logs_user_id ON activity_logs (user_id);
@@ -0,0 +1,14 @@
+-- Human-readable slugs for posts. Existing posts get a slug on server startup.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_slug ON posts (slug);
+
+-- Previous slugs keep resolving with a 301 to the current one
+CREATE TABLE IF NOT EXISTS post_slug_redirects (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_slug_redirects_slug ON post_slug_redirects (slug);
+CREATE INDEX IF NOT EXISTS idx_post_slug_redirects_post_id ON post_slug_redirects (post_id);
@@ -0,0 +1,9 @@
+-- Keep activity history after a post is deleted
+ALTER TABLE activity_logs DROP CONSTRAINT IF EXISTS activity_logs_post_id_fkey;
+
+-- Request correlation and before/after snapshots for the audit trail
+ALTER TABLE activity_logs ADD COLUMN IF NOT EXISTS request_id VARCHAR(64);
+ALTER TABLE activity_logs ADD COLUMN IF NOT EXISTS before JSONB;
+ALTER TABLE activity_logs ADD COLUMN IF NOT EXISTS after JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_activity_logs_action ON activity_logs (action);
@@ -0,0 +1,5 @@
+-- Indexes behind the filters and sort options of GET /posts. Each sort column
+-- is paired with id so keyset pages seek on the index.
+CREATE INDEX IF NOT EXISTS idx_posts_updated_at_id ON posts (updated_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_published_at_id ON posts (published_at DESC NULLS LAST, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_title_id ON posts (title, id);
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- Full-text search fallback for GET /posts/search when Elasticsearch is down or
+-- disabled. The 'simple' configuration only lowercases, since Postgres has no
+-- Vietnamese dictionary; titles weigh more than content.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
\ No newline at end of file
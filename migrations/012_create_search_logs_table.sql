@@ -0,0 +1,19 @@
+-- Search analytics: one row per search, with the first result clicked
+CREATE TABLE IF NOT EXISTS search_logs (
+    id SERIAL PRIMARY KEY,
+    query TEXT NOT NULL,
+    normalized_query VARCHAR(255) NOT NULL,
+    result_count BIGINT NOT NULL,
+    engine VARCHAR(20),
+    latency_ms BIGINT NOT NULL,
+    user_id INTEGER,
+    request_id VARCHAR(64),
+    clicked_post_id INTEGER,
+    clicked_at TIMESTAMP,
+    searched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Reports group the searches of a time window by normalized query
+CREATE INDEX IF NOT EXISTS idx_search_logs_searched_at ON search_logs (searched_at);
+CREATE INDEX IF NOT EXISTS idx_search_logs_normalized_query ON search_logs (normalized_query);
+CREATE INDEX IF NOT EXISTS idx_search_logs_user_id ON search_logs (user_id);
@@ -0,0 +1,26 @@
+-- Saved searches and the alerts raised when a new post matches one
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL,
+    tags TEXT[],
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user_id ON saved_searches (user_id);
+
+CREATE TABLE IF NOT EXISTS search_notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    saved_search_id INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL,
+    post_title TEXT,
+    post_slug VARCHAR(255),
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A post is notified once per saved search, even when it is indexed again
+CREATE UNIQUE INDEX IF NOT EXISTS idx_search_notifications_saved_search_post ON search_notifications (saved_search_id, post_id);
+CREATE INDEX IF NOT EXISTS idx_search_notifications_user_id_created_at ON search_notifications (user_id, created_at DESC);